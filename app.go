@@ -10,6 +10,8 @@ import (
 	"encoding/json"
 	"encoding/xml"
 	"net/url"
+	"net"
+	"errors"
 )
 
 type Middleware func(ctx *Context) error
@@ -18,6 +20,13 @@ type Handler interface {
 	Serve(ctx *Context) error
 }
 
+// ShutdownCloser is implemented by handlers releasing resources when the app
+// closes, such as a logger flushing its buffered writes. Other io.Closer
+// handlers are not closed by the app.
+type ShutdownCloser interface {
+	CloseOnShutdown() error
+}
+
 type Renderer interface {
 	Render(ctx *Context, w io.Writer, name string, data interface{}) error
 }
@@ -78,10 +87,16 @@ type App struct {
 	compress Compressible
 	timeout time.Duration
 	serverName string
+	trustedProxies []*net.IPNet
 	logger *log.Logger
 	onerror func(*Context, HTTPError)
+	onpanic []func(*Context, *Error)
+	repanic bool
+	problemDetails bool
+	messages *MessageCatalog
 	withContext func(*http.Request) context.Context
 	settings map[interface{}]interface{}
+	closeHooks []func()
 }
 
 func New() *App {
@@ -102,8 +117,21 @@ func New() *App {
 	return app
 }
 
+func (app *App) Use(handle Middleware) {
+	app.mds = append(app.mds, handle)
+}
+
+// UseHandler adds the handler as a middleware. A ShutdownCloser handler is
+// closed by App.Close, after the server shut down.
 func (app *App) UseHandler(h Handler) {
 	app.mds = append(app.mds, h.Serve)
+	if c, ok := h.(ShutdownCloser); ok {
+		app.OnClose(func() {
+			if err := c.CloseOnShutdown(); err != nil {
+				app.Error(err)
+			}
+		})
+	}
 }
 
 type appSetting uint8
@@ -130,6 +158,28 @@ const (
 	SetEnv
 
 	SetServerName
+
+	// SetTrustedProxy sets the proxies, a []string of IP addresses or CIDRs, whose
+	// Forwarded and X-Forwarded-* headers are trusted by Context.IP, Context.Host
+	// and Context.Protocol. Proxy headers are ignored if not set.
+	SetTrustedProxy
+
+	// SetOnPanic adds a func(ctx *Context, err *Error) hook called with the
+	// recovered panic and its stack, such as to report to error trackers. It
+	// can be set several times, the hooks run in the order they were added.
+	SetOnPanic
+
+	// SetRepanic re-panics after responding a recovered panic, only when the env
+	// is "development", so that debuggers and tests see the original panic.
+	SetRepanic
+
+	// SetProblemDetails responds errors as RFC 7807 application/problem+json,
+	// or as the "error" template of the Renderer to clients preferring HTML.
+	SetProblemDetails
+
+	// SetMessageCatalog sets a *MessageCatalog to localize the error messages
+	// by the Accept-Language header of requests.
+	SetMessageCatalog
 )
 
 func (app *App) Set(key, val interface{}) {
@@ -199,6 +249,38 @@ func (app *App) Set(key, val interface{}) {
 			} else {
 				app.serverName = name
 			}
+		case SetTrustedProxy:
+			if vals, ok := val.([]string); !ok {
+				panic(Err.WithMsg("SetTrustedProxy setting must be []string"))
+			} else if proxies, err := parseTrustedProxies(vals); err != nil {
+				panic(Err.WithMsgf("SetTrustedProxy setting: %s", err))
+			} else {
+				app.trustedProxies = proxies
+			}
+		case SetOnPanic:
+			if onpanic, ok := val.(func(ctx *Context, err *Error)); !ok {
+				panic(Err.WithMsg("SetOnPanic setting must be func(ctx *Context, err *Error)"))
+			} else {
+				app.onpanic = append(app.onpanic, onpanic)
+			}
+		case SetRepanic:
+			if repanic, ok := val.(bool); !ok {
+				panic(Err.WithMsg("SetRepanic setting must be bool"))
+			} else {
+				app.repanic = repanic
+			}
+		case SetProblemDetails:
+			if problemDetails, ok := val.(bool); !ok {
+				panic(Err.WithMsg("SetProblemDetails setting must be bool"))
+			} else {
+				app.problemDetails = problemDetails
+			}
+		case SetMessageCatalog:
+			if messages, ok := val.(*MessageCatalog); !ok {
+				panic(Err.WithMsg("SetMessageCatalog setting must be *MessageCatalog instance"))
+			} else {
+				app.messages = messages
+			}
 		}
 		app.settings[k] = val
 		return
@@ -206,6 +288,11 @@ func (app *App) Set(key, val interface{}) {
 	app.settings[key] = val
 }
 
+// Setting returns the value of the setting key, or nil if not set.
+func (app *App) Setting(key interface{}) interface{} {
+	return app.settings[key]
+}
+
 func (app *App) Listen(addr string) error {
 	app.Server.Addr = addr
 	app.Server.ErrorLog = app.logger
@@ -213,14 +300,55 @@ func (app *App) Listen(addr string) error {
 	return app.Server.ListenAndServe()
 }
 
+// OnClose adds a hook to run by Close, after the server shut down, to release
+// resources such as log writers.
+func (app *App) OnClose(hook func()) {
+	app.closeHooks = append(app.closeHooks, hook)
+}
+
+// Close gracefully shuts down the server, waiting for active requests until
+// the optional context is done, then runs the OnClose hooks. Hooks registered
+// by app.Server.RegisterOnShutdown run when the shutdown starts.
+func (app *App) Close(ctx ...context.Context) error {
+	c := context.Background()
+	if len(ctx) > 0 {
+		c = ctx[0]
+	}
+	err := app.Server.Shutdown(c)
+	for _, hook := range app.closeHooks {
+		hook()
+	}
+	return err
+}
+
 func (app *App) Error(err error) {
 	if err := ErrorWithStack(err, 4); err != nil {
 		app.logger.Println(err.String())
 	}
 }
 
+// handlePanic responds the recovered panic value as a 500 error with stack.
+func (app *App) handlePanic(ctx *Context, val interface{}) {
+	err := ErrorWithStack(val, 2)
+	ctx.Res.afterHooks = nil
+	ctx.Res.ResetHeader()
+	for _, hook := range app.onpanic {
+		hook(ctx, err)
+	}
+	ctx.respondError(err)
+	if app.repanic && app.settings[SetEnv] == "development" {
+		panic(val)
+	}
+}
+
 func (app *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx := NewContext(app, w, r)
+	// run the complete hooks last, after the compressed body is flushed
+	defer func() {
+		if hooks := ctx.Res.completeHooks; len(hooks) > 0 {
+			go runHooks(hooks)
+		}
+	}()
 
 	if compressWriter := ctx.handleCompress(); compressWriter != nil {
 		defer compressWriter.Close()
@@ -228,10 +356,8 @@ func (app *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// recover panic error
 	defer func() {
-		if err := recover(); err != nil && err != http.ErrAbortHandler {
-			ctx.Res.afterHooks = nil
-			ctx.Res.ResetHeader()
-			ctx.respondError(ErrorWithStack(err))
+		if val := recover(); val != nil && val != http.ErrAbortHandler {
+			app.handlePanic(ctx, val)
 		}
 	}()
 
@@ -256,6 +382,8 @@ func (app *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		err = ErrGatewayTimeout.WithMsg(e.Error())
+	} else if errors.Is(err, context.DeadlineExceeded) {
+		err = ErrGatewayTimeout.WithMsg(err.Error())
 	}
 
 	if !IsNil(err) {
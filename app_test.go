@@ -3,6 +3,9 @@ package goblog
 import (
 	"testing"
 	"fmt"
+	"strings"
+	"net/http/httptest"
+	"time"
 )
 
 func TestNew(t *testing.T) {
@@ -10,3 +13,79 @@ func TestNew(t *testing.T) {
 	fmt.Println()
 	fmt.Println(app)
 }
+
+func TestTimeout(t *testing.T) {
+	app := New()
+	app.Set(SetTimeout, 10*time.Millisecond)
+	app.Use(func(ctx *Context) error {
+		if ctx.Path == "/slow" {
+			return Timeout(time.Second)(ctx)
+		}
+		return Timeout(time.Millisecond)(ctx)
+	})
+	app.Use(func(ctx *Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(20 * time.Millisecond):
+			return ctx.End(200)
+		}
+	})
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/slow", nil))
+	if res.Code != 200 {
+		t.Fatalf("expected extended deadline, got %d", res.Code)
+	}
+
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/fast", nil))
+	if res.Code != 504 {
+		t.Fatalf("expected 504, got %d", res.Code)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	var recovered *Error
+	calls := []string{}
+	app := New()
+	app.Set(SetEnv, "development")
+	app.Set(SetOnPanic, func(ctx *Context, err *Error) {
+		calls = append(calls, "metrics")
+	})
+	app.Set(SetOnPanic, func(ctx *Context, err *Error) {
+		calls = append(calls, "reporter")
+		recovered = err
+	})
+	app.Use(func(ctx *Context) error {
+		panic("boom")
+	})
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/", nil))
+	if res.Code != 500 || recovered == nil || recovered.Msg != "boom" || recovered.Stack == "" {
+		t.Fatalf("unexpected recovery %d %#v", res.Code, recovered)
+	}
+	if strings.Join(calls, ",") != "metrics,reporter" {
+		t.Fatalf("expected all panic hooks in order, got %v", calls)
+	}
+	if !strings.Contains(res.Body.String(), `"stack":`) {
+		t.Fatalf("expected stack in development, got %s", res.Body.String())
+	}
+
+	app.Set(SetEnv, "production")
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/", nil))
+	if strings.Contains(res.Body.String(), `"stack":`) {
+		t.Fatalf("unexpected stack in production: %s", res.Body.String())
+	}
+
+	app.Set(SetEnv, "development")
+	app.Set(SetRepanic, true)
+	defer func() {
+		if val := recover(); val != "boom" {
+			t.Fatalf("expected re-panic, got %v", val)
+		}
+	}()
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
@@ -0,0 +1,61 @@
+// Package auth provides HTTP Basic and Bearer authentication middlewares.
+//
+//	router.Get("/admin", auth.Basic(auth.BasicOptions{
+//		Realm:     "admin",
+//		Validator: auth.Users(map[string]string{"admin": "secret"}),
+//	}), admin)
+//
+// The authenticated principal is stored on the context:
+//
+//	user := auth.FromCtx(ctx)
+package auth
+
+import (
+	"strings"
+
+	"goblog"
+)
+
+type principalKey struct{}
+
+// PrincipalKey is the key of the authenticated principal, retrievable
+// through ctx.Any(auth.PrincipalKey).
+var PrincipalKey interface{} = principalKey{}
+
+// FromCtx returns the authenticated principal, or nil if the request is
+// not authenticated.
+func FromCtx(ctx *goblog.Context) interface{} {
+	val, _ := ctx.Any(PrincipalKey)
+	return val
+}
+
+// challenge responds a 401 error with the WWW-Authenticate header.
+func challenge(ctx *goblog.Context, scheme string, params [][2]string, msg string) error {
+	buf := new(strings.Builder)
+	buf.WriteString(scheme)
+	for i, param := range params {
+		if i == 0 {
+			buf.WriteByte(' ')
+		} else {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(param[0])
+		buf.WriteString(`="`)
+		buf.WriteString(quoteEscaper.Replace(param[1]))
+		buf.WriteByte('"')
+	}
+	ctx.Set(goblog.HeaderWWWAuthenticate, buf.String())
+	return goblog.ErrUnauthorized.WithMsg(msg)
+}
+
+var quoteEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// credentials returns the credentials of the Authorization header if it
+// uses the scheme, scheme is case-insensitive.
+func credentials(ctx *goblog.Context, scheme string) (string, bool) {
+	val := ctx.Get(goblog.HeaderAuthorization)
+	if len(val) <= len(scheme) || !strings.EqualFold(val[:len(scheme)], scheme) || val[len(scheme)] != ' ' {
+		return "", false
+	}
+	return strings.TrimSpace(val[len(scheme)+1:]), true
+}
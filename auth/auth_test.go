@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"goblog"
+)
+
+func serve(app *goblog.App, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/", nil)
+	if authorization != "" {
+		req.Header.Set(goblog.HeaderAuthorization, authorization)
+	}
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	return res
+}
+
+func TestBasic(t *testing.T) {
+	app := goblog.New()
+	app.Use(Basic(BasicOptions{Realm: "test", Validator: Users(map[string]string{"admin": "secret"})}))
+	app.Use(func(ctx *goblog.Context) error {
+		return ctx.HTML(200, FromCtx(ctx).(string))
+	})
+
+	res := serve(app, "")
+	if res.Code != 401 || res.Header().Get(goblog.HeaderWWWAuthenticate) != `Basic realm="test", charset="UTF-8"` {
+		t.Fatalf("expected 401 challenge, got %d %q", res.Code, res.Header().Get(goblog.HeaderWWWAuthenticate))
+	}
+	if res := serve(app, "Basic YWRtaW46d3Jvbmc="); res.Code != 401 {
+		t.Fatalf("expected 401 for wrong password, got %d", res.Code)
+	}
+	if res := serve(app, "basic YWRtaW46c2VjcmV0"); res.Code != 200 || res.Body.String() != "admin" {
+		t.Fatalf("expected admin principal, got %d %q", res.Code, res.Body.String())
+	}
+}
+
+func TestBearer(t *testing.T) {
+	app := goblog.New()
+	app.Use(Bearer(BearerOptions{Verifier: func(ctx *goblog.Context, token string) (interface{}, error) {
+		if token != "token" {
+			return nil, errors.New("token expired")
+		}
+		return "user", nil
+	}}))
+	app.Use(func(ctx *goblog.Context) error {
+		return ctx.HTML(200, FromCtx(ctx).(string))
+	})
+
+	res := serve(app, "Bearer abc")
+	if res.Code != 401 || res.Header().Get(goblog.HeaderWWWAuthenticate) != `Bearer error="invalid_token", error_description="token expired"` {
+		t.Fatalf("expected 401 challenge, got %d %q", res.Code, res.Header().Get(goblog.HeaderWWWAuthenticate))
+	}
+	if res := serve(app, "Bearer token"); res.Code != 200 || res.Body.String() != "user" {
+		t.Fatalf("expected user principal, got %d %q", res.Code, res.Body.String())
+	}
+}
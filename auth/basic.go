@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+
+	"goblog"
+)
+
+// BasicValidator validates the username and password, and returns the
+// authenticated principal.
+type BasicValidator func(ctx *goblog.Context, username, password string) (principal interface{}, ok bool)
+
+type BasicOptions struct {
+	// Realm is the protection space sent in WWW-Authenticate, default to "Restricted".
+	Realm string
+	// Validator is required.
+	Validator BasicValidator
+}
+
+// Basic returns a RFC 7617 HTTP Basic authentication middleware.
+func Basic(opts BasicOptions) goblog.Middleware {
+	if opts.Validator == nil {
+		panic(goblog.Err.WithMsg("auth: BasicOptions.Validator required"))
+	}
+	if opts.Realm == "" {
+		opts.Realm = "Restricted"
+	}
+	params := [][2]string{{"realm", opts.Realm}, {"charset", "UTF-8"}}
+
+	return func(ctx *goblog.Context) error {
+		cred, ok := credentials(ctx, "Basic")
+		if !ok {
+			return challenge(ctx, "Basic", params, "authorization required")
+		}
+		buf, err := base64.StdEncoding.DecodeString(cred)
+		if err != nil {
+			return challenge(ctx, "Basic", params, "invalid basic credentials")
+		}
+		index := strings.IndexByte(string(buf), ':')
+		if index < 0 {
+			return challenge(ctx, "Basic", params, "invalid basic credentials")
+		}
+
+		principal, ok := opts.Validator(ctx, string(buf[:index]), string(buf[index+1:]))
+		if !ok {
+			return challenge(ctx, "Basic", params, "invalid username or password")
+		}
+		if principal == nil {
+			principal = string(buf[:index])
+		}
+		ctx.SetAny(PrincipalKey, principal)
+		return nil
+	}
+}
+
+// Users returns a BasicValidator checking against the username:password
+// map in constant time. The principal is the username.
+func Users(users map[string]string) BasicValidator {
+	hashes := make(map[string][32]byte, len(users))
+	for user, pass := range users {
+		hashes[user] = sha256.Sum256([]byte(pass))
+	}
+	return func(_ *goblog.Context, username, password string) (interface{}, bool) {
+		// always compare to not reveal whether the user exists
+		expected, exists := hashes[username]
+		actual := sha256.Sum256([]byte(password))
+		if subtle.ConstantTimeCompare(expected[:], actual[:]) == 1 && exists {
+			return username, true
+		}
+		return nil, false
+	}
+}
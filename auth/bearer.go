@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"net/http"
+
+	"goblog"
+)
+
+// BearerVerifier verifies the token and returns the authenticated principal.
+type BearerVerifier func(ctx *goblog.Context, token string) (principal interface{}, err error)
+
+type BearerOptions struct {
+	// Realm is the protection space sent in WWW-Authenticate, optional.
+	Realm string
+	// Verifier is required.
+	Verifier BearerVerifier
+}
+
+// Bearer returns a RFC 6750 Bearer token authentication middleware. When the
+// verifier returns a *goblog.Error, its status other than 401 is respected,
+// such as 403 for insufficient scope.
+func Bearer(opts BearerOptions) goblog.Middleware {
+	if opts.Verifier == nil {
+		panic(goblog.Err.WithMsg("auth: BearerOptions.Verifier required"))
+	}
+	var params [][2]string
+	if opts.Realm != "" {
+		params = append(params, [2]string{"realm", opts.Realm})
+	}
+
+	return func(ctx *goblog.Context) error {
+		token, ok := credentials(ctx, "Bearer")
+		if !ok || token == "" {
+			return challenge(ctx, "Bearer", params, "authorization required")
+		}
+
+		principal, err := opts.Verifier(ctx, token)
+		if err != nil {
+			msg := err.Error()
+			if e, ok := err.(*goblog.Error); ok {
+				if e.Code != http.StatusUnauthorized {
+					return e
+				}
+				msg = e.Msg
+			}
+			return challenge(ctx, "Bearer", append(params[:len(params):len(params)],
+				[2]string{"error", "invalid_token"}, [2]string{"error_description", msg}), msg)
+		}
+		ctx.SetAny(PrincipalKey, principal)
+		return nil
+	}
+}
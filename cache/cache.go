@@ -0,0 +1,314 @@
+// Package cache provides a shared response cache middleware for goblog. Entries
+// are keyed by the URL and the request headers listed in the Vary header.
+//
+//	app.Use(cache.New(cache.Options{
+//		Store:      cache.NewMemoryStore(1000),
+//		DefaultTTL: time.Minute,
+//		Handler:    app, // refreshes stale entries in the background
+//	}))
+//
+// Only GET and HEAD requests without Authorization are cached. Responses are
+// stored according to their Cache-Control and Vary headers, responses with a
+// Content-Security-Policy nonce are not stored. Requests can bypass the cache
+// with Cache-Control: no-cache or no-store, and restrict the cached responses
+// with max-age, min-fresh and max-stale.
+package cache
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"goblog"
+)
+
+// HeaderXCache reports HIT, STALE or MISS.
+const HeaderXCache = "X-Cache"
+
+type refreshKey struct{}
+
+type Options struct {
+	// Store is required.
+	Store Store
+	// DefaultTTL is the freshness of responses without max-age, such
+	// responses are not cached if 0.
+	DefaultTTL time.Duration
+	// StaleWhileRevalidate is used when responses don't carry the
+	// stale-while-revalidate directive.
+	StaleWhileRevalidate time.Duration
+	// Handler serves the background revalidation of stale entries, it is
+	// usually the *goblog.App. Stale entries are not served if nil.
+	Handler http.Handler
+}
+
+type cache struct {
+	opts       Options
+	refreshing sync.Map
+}
+
+// New returns the response cache middleware.
+func New(opts Options) goblog.Middleware {
+	if opts.Store == nil {
+		panic(goblog.Err.WithMsg("cache: Options.Store required"))
+	}
+	c := &cache{opts: opts}
+	return c.serve
+}
+
+func (c *cache) serve(ctx *goblog.Context) error {
+	if ctx.Method != http.MethodGet && ctx.Method != http.MethodHead {
+		return nil
+	}
+	if ctx.Get(goblog.HeaderAuthorization) != "" {
+		return nil
+	}
+	reqCC := parseCacheControl(ctx.Req.Header.Values(goblog.HeaderCacheControl))
+	if _, ok := reqCC["no-store"]; ok {
+		return nil
+	}
+
+	// HEAD requests are served from GET responses
+	key := http.MethodGet + " " + ctx.Host + ctx.Req.URL.RequestURI()
+	_, noCache := reqCC["no-cache"]
+	if ctx.Req.Context().Value(refreshKey{}) == nil && !noCache && reqCC["max-age"] != "0" {
+		if entry, varyKey, ok := c.lookup(ctx, key); ok {
+			if state := c.stateOf(entry, time.Now(), reqCC); state != "" {
+				return c.respond(ctx, key+varyKey, entry, state)
+			}
+		}
+	}
+
+	ctx.Set(HeaderXCache, "MISS")
+	if ctx.Method == http.MethodGet {
+		ctx.After(func() { c.store(ctx, key) })
+	}
+	return nil
+}
+
+func (c *cache) lookup(ctx *goblog.Context, key string) (*Entry, string, bool) {
+	entry, ok := c.opts.Store.Get(key)
+	if !ok {
+		return nil, "", false
+	}
+	if entry.Status != 0 {
+		return entry, "", true
+	}
+	varyKey := varyKeyOf(ctx.Req.Header, entry.Vary)
+	entry, ok = c.opts.Store.Get(key + varyKey)
+	return entry, varyKey, ok && entry.Status != 0
+}
+
+// stateOf returns HIT for fresh entries, STALE for entries that may be served
+// while revalidating or that the request accepts stale, or "" for entries that
+// must be regenerated. Stores are not required to evict expired entries.
+func (c *cache) stateOf(entry *Entry, now time.Time, reqCC map[string]string) string {
+	if maxAge, ok := parseSeconds(reqCC, "max-age"); ok && now.Sub(entry.Created) > maxAge {
+		return ""
+	}
+	if minFresh, ok := parseSeconds(reqCC, "min-fresh"); ok {
+		if now.Add(minFresh).Before(entry.Expires) {
+			return "HIT"
+		}
+		return ""
+	}
+	if now.Before(entry.Expires) {
+		return "HIT"
+	}
+	if val, ok := reqCC["max-stale"]; ok {
+		// max-stale without value accepts any staleness
+		if maxStale, ok := parseSeconds(reqCC, "max-stale"); val == "" || (ok && now.Sub(entry.Expires) <= maxStale) {
+			return "STALE"
+		}
+	}
+	if c.opts.Handler != nil && now.Before(entry.StaleUntil) {
+		return "STALE"
+	}
+	return ""
+}
+
+func (c *cache) respond(ctx *goblog.Context, key string, entry *Entry, state string) error {
+	if state == "STALE" && c.opts.Handler != nil {
+		c.refresh(ctx, key)
+	}
+
+	now := time.Now()
+	header := ctx.Res.Header()
+	for name, vals := range entry.Header {
+		header[name] = append([]string(nil), vals...)
+	}
+	ctx.Set(goblog.HeaderAge, strconv.Itoa(int(now.Sub(entry.Created)/time.Second)))
+	ctx.Set(HeaderXCache, state)
+	return ctx.End(entry.Status, entry.Body)
+}
+
+// refresh regenerates the stale entry in the background, once at a time.
+func (c *cache) refresh(ctx *goblog.Context, key string) {
+	if _, loaded := c.refreshing.LoadOrStore(key, true); loaded {
+		return
+	}
+	req := ctx.Req.Clone(context.WithValue(context.Background(), refreshKey{}, true))
+	req.Method = http.MethodGet
+	go func() {
+		defer c.refreshing.Delete(key)
+		c.opts.Handler.ServeHTTP(&discardWriter{header: make(http.Header)}, req)
+	}()
+}
+
+// store saves the response in the after hook, when status, headers and body
+// are final.
+func (c *cache) store(ctx *goblog.Context, key string) {
+	status := ctx.Res.Status()
+	body := ctx.Res.Body()
+	if (body == nil && status != http.StatusNoContent) || !isCacheableStatus(status) {
+		return
+	}
+	header := ctx.Res.Header()
+	if header.Get(goblog.HeaderSetCookie) != "" {
+		return
+	}
+	// a nonce is per request, it must not be replayed to other clients
+	for _, name := range []string{goblog.HeaderContentSecurityPolicy, goblog.HeaderContentSecurityPolicyReportOnly} {
+		if strings.Contains(header.Get(name), "'nonce-") {
+			return
+		}
+	}
+
+	resCC := parseCacheControl(header.Values(goblog.HeaderCacheControl))
+	for _, directive := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := resCC[directive]; ok {
+			return
+		}
+	}
+	ttl := c.opts.DefaultTTL
+	if age, ok := parseSeconds(resCC, "s-maxage"); ok {
+		ttl = age
+	} else if age, ok := parseSeconds(resCC, "max-age"); ok {
+		ttl = age
+	}
+	if ttl <= 0 {
+		return
+	}
+	swr := c.opts.StaleWhileRevalidate
+	if age, ok := parseSeconds(resCC, "stale-while-revalidate"); ok {
+		swr = age
+	}
+
+	var vary []string
+	for _, val := range header.Values(goblog.HeaderVary) {
+		for _, name := range strings.Split(val, ",") {
+			if name = http.CanonicalHeaderKey(strings.TrimSpace(name)); name == "*" {
+				return
+			} else if name != "" {
+				vary = append(vary, name)
+			}
+		}
+	}
+	sort.Strings(vary)
+
+	now := time.Now()
+	entry := &Entry{
+		Status:     status,
+		Header:     cloneHeader(header),
+		Body:       body,
+		Vary:       vary,
+		Created:    now,
+		Expires:    now.Add(ttl),
+		StaleUntil: now.Add(ttl + swr),
+	}
+	if len(vary) == 0 {
+		c.opts.Store.Set(key, entry)
+		return
+	}
+	c.opts.Store.Set(key, &Entry{Vary: vary, Created: now, Expires: entry.Expires, StaleUntil: entry.StaleUntil})
+	c.opts.Store.Set(key+varyKeyOf(ctx.Req.Header, vary), entry)
+}
+
+// uncachedHeaders are hop-by-hop or per-request headers.
+var uncachedHeaders = map[string]bool{}
+
+func init() {
+	for _, name := range []string{goblog.HeaderSetCookie, goblog.HeaderAge, HeaderXCache, goblog.HeaderXRequestID,
+		goblog.HeaderXRateLimitLimit, goblog.HeaderXRateLimitRemaining, goblog.HeaderXRateLimitReset,
+		goblog.HeaderRetryAfter, "Date", "Connection", "Keep-Alive", "Transfer-Encoding"} {
+		uncachedHeaders[http.CanonicalHeaderKey(name)] = true
+	}
+}
+
+// cloneHeader copies the end-to-end headers of the response.
+func cloneHeader(header http.Header) http.Header {
+	res := make(http.Header, len(header))
+	for name, vals := range header {
+		if !uncachedHeaders[http.CanonicalHeaderKey(name)] {
+			res[name] = append([]string(nil), vals...)
+		}
+	}
+	return res
+}
+
+func varyKeyOf(header http.Header, vary []string) string {
+	buf := new(strings.Builder)
+	for _, name := range vary {
+		buf.WriteString("\n")
+		buf.WriteString(name)
+		buf.WriteString(":")
+		buf.WriteString(strings.Join(header.Values(name), ","))
+	}
+	return buf.String()
+}
+
+func parseCacheControl(vals []string) map[string]string {
+	res := make(map[string]string)
+	for _, val := range vals {
+		for _, directive := range strings.Split(val, ",") {
+			directive = strings.TrimSpace(directive)
+			if directive == "" {
+				continue
+			}
+			if index := strings.IndexByte(directive, '='); index >= 0 {
+				res[strings.ToLower(directive[:index])] = strings.Trim(directive[index+1:], `"`)
+			} else {
+				res[strings.ToLower(directive)] = ""
+			}
+		}
+	}
+	return res
+}
+
+func parseSeconds(cc map[string]string, directive string) (time.Duration, bool) {
+	val, ok := cc[directive]
+	if !ok {
+		return 0, false
+	}
+	sec, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || sec < 0 {
+		return 0, false
+	}
+	return time.Duration(sec) * time.Second, true
+}
+
+func isCacheableStatus(status int) bool {
+	switch status {
+	case 200, 203, 204, 300, 301, 308, 404, 405, 410, 414, 501:
+		return true
+	default:
+		return false
+	}
+}
+
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardWriter) Write(buf []byte) (int, error) {
+	return len(buf), nil
+}
+
+func (w *discardWriter) WriteHeader(int) {}
@@ -0,0 +1,187 @@
+package cache
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"goblog"
+)
+
+func TestCache(t *testing.T) {
+	count := 0
+	app := goblog.New()
+	app.Use(New(Options{Store: NewMemoryStore(10), DefaultTTL: time.Minute}))
+	app.Use(func(ctx *goblog.Context) error {
+		count++
+		ctx.Res.Vary("Accept-Language")
+		return ctx.HTML(200, ctx.Get("Accept-Language"))
+	})
+
+	serve := func(lang string, cacheControl string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/post/1", nil)
+		req.Header.Set("Accept-Language", lang)
+		if cacheControl != "" {
+			req.Header.Set(goblog.HeaderCacheControl, cacheControl)
+		}
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+		return res
+	}
+
+	serve("en", "")
+	res := serve("en", "")
+	if res.Header().Get(HeaderXCache) != "HIT" || res.Body.String() != "en" || count != 1 {
+		t.Fatalf("expected cache hit, got %s %q after %d renders", res.Header().Get(HeaderXCache), res.Body.String(), count)
+	}
+	if res = serve("zh", ""); res.Header().Get(HeaderXCache) != "MISS" || res.Body.String() != "zh" {
+		t.Fatalf("expected another variant, got %s %q", res.Header().Get(HeaderXCache), res.Body.String())
+	}
+	if res = serve("en", "no-cache"); res.Header().Get(HeaderXCache) != "MISS" || count != 3 {
+		t.Fatalf("expected no-cache to bypass the cache, got %s", res.Header().Get(HeaderXCache))
+	}
+}
+
+// mapStore keeps expired entries, it signals every Set.
+type mapStore struct {
+	mu      sync.Mutex
+	entries map[string]*Entry
+	sets    chan string
+}
+
+func newMapStore() *mapStore {
+	return &mapStore{entries: make(map[string]*Entry), sets: make(chan string, 10)}
+}
+
+func (s *mapStore) Get(key string) (*Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	return entry, ok
+}
+
+func (s *mapStore) Set(key string, entry *Entry) {
+	s.mu.Lock()
+	s.entries[key] = entry
+	s.mu.Unlock()
+	s.sets <- key
+}
+
+func (s *mapStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+}
+
+// newCountApp returns an app responding with the number of renders, it
+// revalidates stale entries if revalidate.
+func newCountApp(opts Options, revalidate bool) *goblog.App {
+	var count int32
+	app := goblog.New()
+	if revalidate {
+		opts.Handler = app
+	}
+	app.Use(New(opts))
+	app.Use(func(ctx *goblog.Context) error {
+		return ctx.HTML(200, strconv.Itoa(int(atomic.AddInt32(&count, 1))))
+	})
+	return app
+}
+
+func get(app *goblog.App) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/post/1", nil))
+	return res
+}
+
+func TestCache_Expired(t *testing.T) {
+	store := newMapStore()
+	app := newCountApp(Options{Store: store, DefaultTTL: 20 * time.Millisecond}, false)
+
+	get(app)
+	<-store.sets
+	time.Sleep(30 * time.Millisecond)
+	if res := get(app); res.Header().Get(HeaderXCache) != "MISS" || res.Body.String() != "2" {
+		t.Fatalf("expected expired entry regenerated, got %s %q", res.Header().Get(HeaderXCache), res.Body.String())
+	}
+	if res := get(app); res.Header().Get(HeaderXCache) != "HIT" || res.Body.String() != "2" {
+		t.Fatalf("expected regenerated entry cached, got %s %q", res.Header().Get(HeaderXCache), res.Body.String())
+	}
+}
+
+func TestCache_StaleWhileRevalidate(t *testing.T) {
+	store := newMapStore()
+	app := newCountApp(Options{Store: store, DefaultTTL: 20 * time.Millisecond, StaleWhileRevalidate: time.Minute}, true)
+
+	get(app)
+	<-store.sets
+	time.Sleep(30 * time.Millisecond)
+	if res := get(app); res.Header().Get(HeaderXCache) != "STALE" || res.Body.String() != "1" {
+		t.Fatalf("expected stale entry served, got %s %q", res.Header().Get(HeaderXCache), res.Body.String())
+	}
+	select {
+	case <-store.sets:
+	case <-time.After(time.Second):
+		t.Fatal("expected stale entry revalidated")
+	}
+	if res := get(app); res.Header().Get(HeaderXCache) != "HIT" || res.Body.String() != "2" {
+		t.Fatalf("expected revalidated entry, got %s %q", res.Header().Get(HeaderXCache), res.Body.String())
+	}
+}
+
+func TestCache_RequestDirectives(t *testing.T) {
+	store := newMapStore()
+	app := newCountApp(Options{Store: store, DefaultTTL: 20 * time.Millisecond}, false)
+	serve := func(cacheControl string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/post/1", nil)
+		req.Header.Set(goblog.HeaderCacheControl, cacheControl)
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+		return res
+	}
+
+	get(app)
+	if res := serve("min-fresh=60"); res.Header().Get(HeaderXCache) != "MISS" || res.Body.String() != "2" {
+		t.Fatalf("expected min-fresh to refuse a soon stale entry, got %s %q", res.Header().Get(HeaderXCache), res.Body.String())
+	}
+	time.Sleep(30 * time.Millisecond)
+	if res := serve("max-stale=60"); res.Header().Get(HeaderXCache) != "STALE" || res.Body.String() != "2" {
+		t.Fatalf("expected max-stale to accept the stale entry, got %s %q", res.Header().Get(HeaderXCache), res.Body.String())
+	}
+	if res := serve("max-stale"); res.Header().Get(HeaderXCache) != "STALE" {
+		t.Fatalf("expected max-stale to accept any stale entry, got %s", res.Header().Get(HeaderXCache))
+	}
+	if res := get(app); res.Header().Get(HeaderXCache) != "MISS" || res.Body.String() != "3" {
+		t.Fatalf("expected the stale entry regenerated, got %s %q", res.Header().Get(HeaderXCache), res.Body.String())
+	}
+}
+
+func TestCache_Nonce(t *testing.T) {
+	count := 0
+	app := goblog.New()
+	app.Use(New(Options{Store: NewMemoryStore(10), DefaultTTL: time.Minute}))
+	app.Use(func(ctx *goblog.Context) error {
+		count++
+		ctx.Set(goblog.HeaderContentSecurityPolicy, "script-src 'nonce-"+strconv.Itoa(count)+"'")
+		return ctx.HTML(200, "<script nonce=\""+strconv.Itoa(count)+"\"></script>")
+	})
+
+	get(app)
+	if res := get(app); res.Header().Get(HeaderXCache) != "MISS" || count != 2 {
+		t.Fatalf("expected responses with a nonce not cached, got %s", res.Header().Get(HeaderXCache))
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore(2)
+	s.Set("a", &Entry{Status: 200})
+	s.Set("b", &Entry{Status: 200})
+	s.Get("a")
+	s.Set("c", &Entry{Status: 200})
+	if _, ok := s.Get("b"); ok || s.Len() != 2 {
+		t.Fatal("expected the least recently used entry evicted")
+	}
+}
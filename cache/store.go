@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+
+	"goblog"
+)
+
+// Entry is a cached response.
+type Entry struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	// Vary lists the request headers the response varies on. An entry
+	// without Status is an index pointing to the variants of a resource.
+	Vary []string
+	// Created is the time the response was generated.
+	Created time.Time
+	// Expires is the time the response becomes stale.
+	Expires time.Time
+	// StaleUntil is the time until which the stale response may be served
+	// while revalidating it in the background.
+	StaleUntil time.Time
+}
+
+// Store keeps cached entries. Implementations must be safe for concurrent
+// use, and may drop entries at any time.
+type Store interface {
+	Get(key string) (*Entry, bool)
+	Set(key string, entry *Entry)
+	Delete(key string)
+}
+
+type lruItem struct {
+	key   string
+	entry *Entry
+}
+
+// MemoryStore is an in-process LRU Store.
+type MemoryStore struct {
+	mu    sync.Mutex
+	max   int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+// NewMemoryStore returns a MemoryStore keeping at most maxEntries entries,
+// the least recently used entries are evicted first.
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	if maxEntries <= 0 {
+		panic(goblog.Err.WithMsg("cache: maxEntries must be positive"))
+	}
+	return &MemoryStore{
+		max:   maxEntries,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (s *MemoryStore) Get(key string) (*Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	item := el.Value.(*lruItem)
+	if expired(item.entry, time.Now()) {
+		s.remove(el)
+		return nil, false
+	}
+	s.ll.MoveToFront(el)
+	return item.entry, true
+}
+
+func (s *MemoryStore) Set(key string, entry *Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.items[key]; ok {
+		el.Value.(*lruItem).entry = entry
+		s.ll.MoveToFront(el)
+		return
+	}
+	s.items[key] = s.ll.PushFront(&lruItem{key: key, entry: entry})
+	for s.ll.Len() > s.max {
+		s.remove(s.ll.Back())
+	}
+}
+
+func (s *MemoryStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.items[key]; ok {
+		s.remove(el)
+	}
+}
+
+// Len returns the number of entries.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ll.Len()
+}
+
+func (s *MemoryStore) remove(el *list.Element) {
+	s.ll.Remove(el)
+	delete(s.items, el.Value.(*lruItem).key)
+}
+
+func expired(entry *Entry, now time.Time) bool {
+	until := entry.Expires
+	if entry.StaleUntil.After(until) {
+		until = entry.StaleUntil
+	}
+	return !until.IsZero() && !now.Before(until)
+}
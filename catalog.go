@@ -0,0 +1,88 @@
+package goblog
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+)
+
+// ErrorCatalog declares the application errors of a service once, so that
+// they can be exported as JSON for client SDKs.
+//
+//	var ErrPostNotFound = goblog.DefineError(goblog.ErrNotFound, "POST_NOT_FOUND", "post not found")
+//
+//	router.Get("/errors", func(ctx *goblog.Context) error {
+//		return ctx.JSON(200, goblog.DefaultErrorCatalog)
+//	})
+type ErrorCatalog struct {
+	mu     sync.RWMutex
+	errors map[string]*Error
+}
+
+// DefaultErrorCatalog is the catalog of DefineError.
+var DefaultErrorCatalog = NewErrorCatalog()
+
+func NewErrorCatalog() *ErrorCatalog {
+	return &ErrorCatalog{errors: make(map[string]*Error)}
+}
+
+// Define declares an application error with the status of base, such as
+// ErrNotFound, and a default message. It panics if the code is defined twice.
+func (c *ErrorCatalog) Define(base *Error, appCode, msg string) *Error {
+	if appCode == "" {
+		panic(Err.WithMsg("error catalog: empty app code"))
+	}
+	err := base.WithAppCode(appCode)
+	err.Msg = msg
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.errors[appCode]; ok {
+		panic(Err.WithMsgf("error catalog: duplicate app code %s", appCode))
+	}
+	c.errors[appCode] = err
+	return err
+}
+
+// Lookup returns the error defined with the app code, or nil.
+func (c *ErrorCatalog) Lookup(appCode string) *Error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.errors[appCode]
+}
+
+// Errors returns the defined errors ordered by app code.
+func (c *ErrorCatalog) Errors() []*Error {
+	c.mu.RLock()
+	errs := make([]*Error, 0, len(c.errors))
+	for _, err := range c.errors {
+		errs = append(errs, err)
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(errs, func(i, j int) bool { return errs[i].AppCode < errs[j].AppCode })
+	return errs
+}
+
+type catalogEntry struct {
+	Code    string `json:"code"`
+	Status  int    `json:"status"`
+	Error   string `json:"error"`
+	Message string `json:"message,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler interface, the catalog is exported as
+// an array of {"code", "status", "error", "message"} ordered by code.
+func (c *ErrorCatalog) MarshalJSON() ([]byte, error) {
+	errs := c.Errors()
+	entries := make([]catalogEntry, len(errs))
+	for i, err := range errs {
+		entries[i] = catalogEntry{Code: err.AppCode, Status: err.Code, Error: err.Err, Message: err.Msg}
+	}
+	return json.Marshal(entries)
+}
+
+// DefineError defines an application error in the DefaultErrorCatalog.
+func DefineError(base *Error, appCode, msg string) *Error {
+	return DefaultErrorCatalog.Define(base, appCode, msg)
+}
@@ -36,7 +36,8 @@ func newCompress(res *Response, c Compressible, encoding string) *compressWriter
 func (cw *compressWriter) WriteHeader(code int) {
 	defer cw.rw.WriteHeader(code)
 
-	if !isEmptyStatus(code) &&
+	// skip responses already encoded, such as those relayed by a proxy
+	if !isEmptyStatus(code) && cw.res.Get(HeaderContentEncoding) == "" &&
 		cw.compress.Compressible(cw.res.Get(HeaderContentType), len(cw.res.body)) {
 		var w io.WriteCloser
 
@@ -67,6 +68,15 @@ func (cw *compressWriter) Write(b []byte) (int, error) {
 	return cw.rw.Write(b)
 }
 
+func (cw *compressWriter) Flush() {
+	if f, ok := cw.writer.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := cw.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func (cw *compressWriter) Close() error {
 	if cw.writer != nil {
 		return cw.writer.Close()
@@ -8,35 +8,99 @@ const (
 	MIMEApplicationJSONCharsetUTF8 = "application/json; charset=utf-8"
 	MIMEApplicationXML = "application/xml"
 	MIMEApplicationForm = "application/x-www-form-urlencoded"
+	MIMEApplicationProblemJSON = "application/problem+json"
+	MIMETextHTML = "text/html"
 	MIMETextHTMLCharsetUTF8 = "text/html; charset=utf-8"
 )
 
 // HTTP Header Fields
 const (
 	HeaderAcceptEncoding = "Accept-Encoding"
+	HeaderAcceptLanguage = "Accept-Language"
+	HeaderAuthorization = "Authorization"
+	HeaderCacheControl = "Cache-Control"
 	HeaderContentLength = "Content-Length"
 	HeaderContentType = "Content-Type"
+	HeaderForwarded = "Forwarded"
+	HeaderReferer = "Referer"
 	HeaderUserAgent = "User-Agent"
 
+	HeaderAge = "Age"
 	HeaderAllow = "Allow"
 	HeaderContentEncoding = "Content-Encoding"
+	HeaderRetryAfter = "Retry-After"
 	HeaderServer = "Server"
+	HeaderSetCookie = "Set-Cookie"
 	HeaderVary = "Vary"
+	HeaderWWWAuthenticate = "WWW-Authenticate"
+
+	// Security
+	HeaderContentSecurityPolicy = "Content-Security-Policy"
+	HeaderContentSecurityPolicyReportOnly = "Content-Security-Policy-Report-Only"
+	HeaderCrossOriginEmbedderPolicy = "Cross-Origin-Embedder-Policy"
+	HeaderCrossOriginOpenerPolicy = "Cross-Origin-Opener-Policy"
+	HeaderCrossOriginResourcePolicy = "Cross-Origin-Resource-Policy"
+	HeaderPermissionsPolicy = "Permissions-Policy"
+	HeaderReferrerPolicy = "Referrer-Policy"
+	HeaderStrictTransportSecurity = "Strict-Transport-Security"
+	HeaderXFrameOptions = "X-Frame-Options"
 
 	HeaderXContentTypeOptions = "X-Content-Type-Options"
 	HeaderXForwardedFor = "X-Forwarded-For"
+	HeaderXForwardedHost = "X-Forwarded-Host"
+	HeaderXForwardedProto = "X-Forwarded-Proto"
 	HeaderXRealIP = "X-Real-IP"
+	HeaderXRequestID = "X-Request-ID"
+	HeaderXRateLimitLimit = "X-RateLimit-Limit"
+	HeaderXRateLimitRemaining = "X-RateLimit-Remaining"
+	HeaderXRateLimitReset = "X-RateLimit-Reset"
 )
 
 // Predefine
 var (
 	Err = &Error{Code: http.StatusInternalServerError, Err: "Error"}
 
+	// 4xx
 	ErrBadRequest = Err.WithCode(http.StatusBadRequest)
+	ErrUnauthorized = Err.WithCode(http.StatusUnauthorized)
+	ErrPaymentRequired = Err.WithCode(http.StatusPaymentRequired)
+	ErrForbidden = Err.WithCode(http.StatusForbidden)
+	ErrNotFound = Err.WithCode(http.StatusNotFound)
 	ErrMethodNotAllowed = Err.WithCode(http.StatusMethodNotAllowed)
+	ErrNotAcceptable = Err.WithCode(http.StatusNotAcceptable)
+	ErrProxyAuthRequired = Err.WithCode(http.StatusProxyAuthRequired)
+	ErrRequestTimeout = Err.WithCode(http.StatusRequestTimeout)
+	ErrConflict = Err.WithCode(http.StatusConflict)
+	ErrGone = Err.WithCode(http.StatusGone)
+	ErrLengthRequired = Err.WithCode(http.StatusLengthRequired)
+	ErrPreconditionFailed = Err.WithCode(http.StatusPreconditionFailed)
+	ErrRequestEntityTooLarge = Err.WithCode(http.StatusRequestEntityTooLarge)
+	ErrRequestURITooLong = Err.WithCode(http.StatusRequestURITooLong)
 	ErrUnsupportedMediaType = Err.WithCode(http.StatusUnsupportedMediaType)
-	ErrNotFound = Err.WithCode(http.StatusNotFound)
+	ErrRequestedRangeNotSatisfiable = Err.WithCode(http.StatusRequestedRangeNotSatisfiable)
+	ErrExpectationFailed = Err.WithCode(http.StatusExpectationFailed)
+	ErrTeapot = Err.WithCode(http.StatusTeapot)
+	ErrMisdirectedRequest = Err.WithCode(http.StatusMisdirectedRequest)
+	ErrUnprocessableEntity = Err.WithCode(http.StatusUnprocessableEntity)
+	ErrLocked = Err.WithCode(http.StatusLocked)
+	ErrFailedDependency = Err.WithCode(http.StatusFailedDependency)
+	ErrTooEarly = Err.WithCode(http.StatusTooEarly)
+	ErrUpgradeRequired = Err.WithCode(http.StatusUpgradeRequired)
+	ErrPreconditionRequired = Err.WithCode(http.StatusPreconditionRequired)
+	ErrTooManyRequests = Err.WithCode(http.StatusTooManyRequests)
+	ErrRequestHeaderFieldsTooLarge = Err.WithCode(http.StatusRequestHeaderFieldsTooLarge)
+	ErrUnavailableForLegalReasons = Err.WithCode(http.StatusUnavailableForLegalReasons)
+
+	// 5xx
 	ErrInternalServerError = Err.WithCode(http.StatusInternalServerError)
 	ErrNotImplemented = Err.WithCode(http.StatusNotImplemented)
+	ErrBadGateway = Err.WithCode(http.StatusBadGateway)
+	ErrServiceUnavailable = Err.WithCode(http.StatusServiceUnavailable)
 	ErrGatewayTimeout = Err.WithCode(http.StatusGatewayTimeout)
+	ErrHTTPVersionNotSupported = Err.WithCode(http.StatusHTTPVersionNotSupported)
+	ErrVariantAlsoNegotiates = Err.WithCode(http.StatusVariantAlsoNegotiates)
+	ErrInsufficientStorage = Err.WithCode(http.StatusInsufficientStorage)
+	ErrLoopDetected = Err.WithCode(http.StatusLoopDetected)
+	ErrNotExtended = Err.WithCode(http.StatusNotExtended)
+	ErrNetworkAuthenticationRequired = Err.WithCode(http.StatusNetworkAuthenticationRequired)
 )
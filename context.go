@@ -6,11 +6,11 @@ import (
 	"net/url"
 	"context"
 	"net"
-	"strings"
 	"encoding/json"
 	"github.com/go-http-utils/negotiator"
 	"io"
 	"bytes"
+	"time"
 )
 
 type contextKey int
@@ -19,6 +19,7 @@ const (
 	isContext contextKey = iota
 	isRecursive
 	paramsKey
+	routePatternKey
 )
 
 type Any interface {
@@ -35,10 +36,13 @@ type Context struct {
 	Method string
 	Path   string
 
+	ip 		  net.IP
+	protocol  string
 	query 	  url.Values
 	ctx 	  context.Context
 	_ctx 	  context.Context
 	cancelCtx context.CancelFunc
+	timeoutCtx *timeoutContext
 	kv 		  map[interface{}]interface{}
 }
 
@@ -48,7 +52,6 @@ func NewContext(app *App, w http.ResponseWriter, r *http.Request) *Context {
 		Req: r,
 		Res: &Response{w: w, rw: w},
 
-		Host: r.Host,
 		Method: r.Method,
 		Path: r.URL.Path,
 
@@ -56,16 +59,16 @@ func NewContext(app *App, w http.ResponseWriter, r *http.Request) *Context {
 		kv: make(map[interface{}]interface{}),
 	}
 
+	fwd := app.resolveForwarded(r)
+	ctx.ip, ctx.protocol, ctx.Host = fwd.ip, fwd.proto, fwd.host
+
 	if app.serverName != "" {
 		ctx.Set(HeaderServer, app.serverName)
 	}
 
-	if app.timeout <= 0 {
-		ctx.ctx, ctx.cancelCtx = context.WithCancel(r.Context())
-	} else {
-		ctx.ctx, ctx.cancelCtx = context.WithTimeout(r.Context(), app.timeout)
-	}
-	ctx.ctx = context.WithValue(ctx.ctx, isContext, isContext)
+	// the deadline can be changed later by Timeout middleware
+	ctx.timeoutCtx, ctx.cancelCtx = newTimeoutContext(r.Context(), app.timeout)
+	ctx.ctx = context.WithValue(ctx.timeoutCtx, isContext, isContext)
 
 	if app.withContext != nil {
 		ctx._ctx = app.withContext(r.WithContext(ctx.ctx))
@@ -78,6 +81,26 @@ func NewContext(app *App, w http.ResponseWriter, r *http.Request) *Context {
 	return &ctx
 }
 
+// Context returns the context.Context of the request, created by the
+// SetWithContext setting if present.
+func (ctx *Context) Context() context.Context {
+	return ctx._ctx
+}
+
+// WithContext replaces the context.Context returned by ctx.Context. The new
+// context must be derived from ctx.Context.
+func (ctx *Context) WithContext(c context.Context) {
+	if c.Value(isContext) == nil {
+		panic(Err.WithMsg("the context is not created from gear.Context"))
+	}
+	ctx._ctx = c
+}
+
+// Value returns the value associated with key in ctx.Context.
+func (ctx *Context) Value(key interface{}) interface{} {
+	return ctx._ctx.Value(key)
+}
+
 func (ctx *Context) Done() <-chan struct{} {
 	return ctx.ctx.Done()
 }
@@ -105,22 +128,52 @@ func (ctx *Context) SetAny(key, val interface{}) {
 	ctx.kv[key] = val
 }
 
+// Setting returns the value of the app setting key, or nil if not set.
+func (ctx *Context) Setting(key interface{}) interface{} {
+	return ctx.app.Setting(key)
+}
+
+// IP returns the client IP. Proxy headers are only honored when the request
+// comes from a proxy listed in SetTrustedProxy.
 func (ctx *Context) IP() net.IP {
-	ra := ctx.Req.RemoteAddr
-	if ip := ctx.Req.Header.Get(HeaderXForwardedFor); ip != "" {
-		ra = ip
-	} else if ip := ctx.Req.Header.Get(HeaderXRealIP); ip != "" {
-		ra = ip
-	} else {
-		ra, _, _ = net.SplitHostPort(ra)
-	}
-	if index := strings.IndexByte(ra, ','); index >= 0 {
-		ra = ra[0:index]
+	return ctx.ip
+}
+
+// Protocol returns "https" or "http", as seen by the client.
+func (ctx *Context) Protocol() string {
+	return ctx.protocol
+}
+
+// Param returns the router parameter of the matched route, or "".
+func (ctx *Context) Param(key string) string {
+	if params, ok := ctx.kv[paramsKey].(map[string]string); ok {
+		return params[key]
 	}
-	return net.ParseIP(strings.TrimSpace(ra))
+	return ""
+}
+
+// RoutePattern returns the pattern of the matched route, such as "/post/:id",
+// or "" if no route matched.
+func (ctx *Context) RoutePattern() string {
+	pattern, _ := ctx.kv[routePatternKey].(string)
+	return pattern
 }
 
+// AcceptType returns the most preferred content type of the request from the
+// offers, or "" if none is acceptable.
+func (ctx *Context) AcceptType(preferred ...string) string {
+	return negotiator.New(ctx.Req.Header).Type(preferred...)
+}
+
+// AcceptEncoding returns the most preferred content encoding of the request
+// from the offers, or "" if none is acceptable.
 func (ctx *Context) AcceptEncoding(preferred ...string) string {
+	return negotiator.New(ctx.Req.Header).Encoding(preferred...)
+}
+
+// AcceptLanguage returns the most preferred language of the request from the
+// offers, or "" if none is acceptable.
+func (ctx *Context) AcceptLanguage(preferred ...string) string {
 	return negotiator.New(ctx.Req.Header).Language(preferred...)
 }
 
@@ -180,8 +233,11 @@ func (ctx *Context) Render(code int, name string, data interface{}) (err error)
 	return
 }
 
+// Stream responds the reader content. Streaming is exempt from the request
+// deadline, it lasts until the reader or the client is done.
 func (ctx *Context) Stream(code int, contentType string, r io.Reader) (err error) {
 	if ctx.Res.ended.swapTrue() {
+		ctx.SetDeadline(time.Time{})
 		ctx.Status(code)
 		ctx.Type(contentType)
 		_, err = io.Copy(ctx.Res, r)
@@ -215,6 +271,15 @@ func (ctx *Context) End(code int, buf ...[]byte) (err error) {
 	return
 }
 
+// After adds a hook running before the response header is written, hooks run
+// in LIFO order. They are cleared when responding an error.
+func (ctx *Context) After(hook func()) {
+	if ctx.Res.wroteHeader.isTrue() {
+		panic(Err.WithMsg(`can't add "after hook" after header written`))
+	}
+	ctx.Res.afterHooks = append(ctx.Res.afterHooks, hook)
+}
+
 func (ctx *Context) OnEnd(hook func()) {
 	if ctx.Res.ended.isTrue() {
 		panic(Err.WithMsg(`can't add "end hook" after middleware process ended`))
@@ -222,6 +287,13 @@ func (ctx *Context) OnEnd(hook func()) {
 	ctx.Res.endHooks = append(ctx.Res.endHooks, hook)
 }
 
+// OnComplete adds a hook running asynchronously when the request is complete,
+// after the middlewares returned and the response body was written, such as
+// to measure the request. Hooks run in LIFO order.
+func (ctx *Context) OnComplete(hook func()) {
+	ctx.Res.completeHooks = append(ctx.Res.completeHooks, hook)
+}
+
 func (ctx *Context) respondError(err HTTPError) {
 	if !ctx.Res.wroteHeader.isTrue() {
 		code := err.Status()
@@ -229,15 +301,37 @@ func (ctx *Context) respondError(err HTTPError) {
 		if code == 500 || code > 501 || code < 400 {
 			ctx.app.Error(err)
 		}
-		// try to render error as json
-		ctx.Set(HeaderContentType, MIMEApplicationJSONCharsetUTF8)
 		ctx.Set(HeaderXContentTypeOptions, "nosniff")
+		if ctx.app.messages != nil {
+			err = ctx.localizeError(err)
+		}
+		if ctx.app.problemDetails {
+			ctx.respondProblem(err)
+			return
+		}
 
-		buf, _ := json.Marshal(err)
+		// try to render error as json
+		ctx.Set(HeaderContentType, MIMEApplicationJSONCharsetUTF8)
+		var body interface{} = err
+		if stack := ctx.errorStack(err); stack != "" {
+			body = struct {
+				*Error
+				Stack string `json:"stack"`
+			}{err.(*Error), stack}
+		}
+		buf, _ := json.Marshal(body)
 		ctx.Res.respond(code, buf)
 	}
 }
 
+// errorStack returns the stack of the error, exposed to developers only.
+func (ctx *Context) errorStack(err HTTPError) string {
+	if e, ok := err.(*Error); ok && ctx.app.settings[SetEnv] == "development" {
+		return e.Stack
+	}
+	return ""
+}
+
 func (ctx *Context) handleCompress() (cw *compressWriter) {
 	if ctx.app.compress != nil && ctx.Method != http.MethodHead && ctx.Method != http.MethodOptions {
 		if cw = newCompress(ctx.Res, ctx.app.compress, ctx.AcceptEncoding("gzip", "deflate")); cw != nil {
@@ -0,0 +1,128 @@
+package goblog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContext_IP(t *testing.T) {
+	app := New()
+	app.Set(SetTrustedProxy, []string{"10.0.0.0/8", "192.168.1.1"})
+
+	cases := []struct {
+		remote, header, value, ip, proto, host string
+	}{
+		{"1.2.3.4:80", HeaderXForwardedFor, "5.6.7.8", "1.2.3.4", "http", "example.com"},
+		{"10.0.0.1:80", HeaderXForwardedFor, "5.6.7.8, 9.9.9.9, 10.0.0.2", "9.9.9.9", "http", "example.com"},
+		{"192.168.1.1:80", HeaderXRealIP, "5.6.7.8", "5.6.7.8", "http", "example.com"},
+		{"10.0.0.1:80", HeaderForwarded, `for=5.6.7.8;proto=https;host=blog.com, for="[::1]:80"`, "::1", "http", "example.com"},
+		{"10.0.0.1:80", HeaderForwarded, `for=5.6.7.8;proto=https;host=blog.com, for=10.0.0.3`, "5.6.7.8", "https", "blog.com"},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "http://example.com/", nil)
+		req.RemoteAddr = c.remote
+		req.Header.Set(c.header, c.value)
+		ctx := NewContext(app, httptest.NewRecorder(), req)
+
+		if ip := ctx.IP().String(); ip != c.ip {
+			t.Errorf("%s %q: expected IP %s, got %s", c.remote, c.value, c.ip, ip)
+		}
+		if ctx.Protocol() != c.proto || ctx.Host != c.host {
+			t.Errorf("%s %q: expected %s://%s, got %s://%s", c.remote, c.value, c.proto, c.host, ctx.Protocol(), ctx.Host)
+		}
+	}
+}
+
+type errorRenderer struct{}
+
+func (errorRenderer) Render(ctx *Context, w io.Writer, name string, data interface{}) error {
+	_, err := fmt.Fprintf(w, "<h1>%s</h1>", data.(*Problem).Title)
+	return err
+}
+
+func TestContext_ProblemDetails(t *testing.T) {
+	app := New()
+	app.Set(SetEnv, "production")
+	app.Set(SetProblemDetails, true)
+	app.Use(func(ctx *Context) error {
+		err := ErrNotFound.WithMsg("post 1 not found")
+		err.Data = map[string]interface{}{"type": "https://example.com/not-found", "id": 1, "status": 0}
+		return err
+	})
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/post/1", nil))
+	problem := map[string]interface{}{}
+	json.Unmarshal(res.Body.Bytes(), &problem)
+	if res.Code != 404 || res.Header().Get(HeaderContentType) != MIMEApplicationProblemJSON {
+		t.Fatalf("unexpected response %d %s", res.Code, res.Header().Get(HeaderContentType))
+	}
+	expected := map[string]interface{}{
+		"type": "https://example.com/not-found", "title": "Not Found", "status": 404.0,
+		"detail": "post 1 not found", "instance": "/post/1", "id": 1.0,
+	}
+	if fmt.Sprint(problem) != fmt.Sprint(expected) {
+		t.Fatalf("expected %v, got %v", expected, problem)
+	}
+
+	app.Set(SetRenderer, errorRenderer{})
+	req := httptest.NewRequest("GET", "/post/1", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	if res.Code != 404 || res.Body.String() != "<h1>Not Found</h1>" {
+		t.Fatalf("expected error page, got %d %s", res.Code, res.Body.String())
+	}
+}
+
+func TestContext_LocalizedError(t *testing.T) {
+	messages := NewMessageCatalog("en")
+	messages.Add("en", map[string]string{"POST_NOT_FOUND": "post {id} not found"})
+	messages.Add("zh", map[string]string{"POST_NOT_FOUND": "文章 {id} 不存在", "404": "资源不存在"})
+
+	app := New()
+	app.Set(SetMessageCatalog, messages)
+	app.Use(func(ctx *Context) error {
+		if ctx.Path == "/post/1" {
+			err := ErrNotFound.WithAppCode("POST_NOT_FOUND")
+			err.Data = map[string]interface{}{"id": 1}
+			return err
+		}
+		return ErrNotFound.WithMsg("not found")
+	})
+
+	cases := []struct{ path, lang, msg string }{
+		{"/post/1", "zh", "文章 1 不存在"},
+		{"/post/1", "en", "post 1 not found"},
+		{"/post/1", "fr", "post 1 not found"},
+		{"/other", "zh", "资源不存在"},
+		{"/other", "en", "not found"},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", c.path, nil)
+		req.Header.Set(HeaderAcceptLanguage, c.lang)
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+		body := map[string]interface{}{}
+		json.Unmarshal(res.Body.Bytes(), &body)
+		if body["message"] != c.msg {
+			t.Errorf("%s %s: expected %q, got %q", c.path, c.lang, c.msg, body["message"])
+		}
+	}
+}
+
+func TestContext_Accept(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderAcceptEncoding, "gzip")
+	req.Header.Set(HeaderAcceptLanguage, "zh")
+	ctx := NewContext(New(), httptest.NewRecorder(), req)
+	if encoding := ctx.AcceptEncoding("br", "gzip"); encoding != "gzip" {
+		t.Errorf("expected gzip encoding from Accept-Encoding, got %q", encoding)
+	}
+	if lang := ctx.AcceptLanguage("en", "zh"); lang != "zh" {
+		t.Errorf("expected zh language from Accept-Language, got %q", lang)
+	}
+}
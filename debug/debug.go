@@ -0,0 +1,172 @@
+// Package debug mounts net/http/pprof, expvar and runtime stats routes on a
+// goblog Router. The routes respond 404 when the app env is "production",
+// unless forced.
+//
+//	router := goblog.NewRouter(goblog.RouterOptions{Root: "/debug"})
+//	debug.Mount(router, debug.Options{Auth: auth.Basic(auth.BasicOptions{
+//		Validator: auth.Users(map[string]string{"admin": "secret"}),
+//	})})
+//	app.UseHandler(router)
+//
+// Routes, relative to the router root:
+//
+//	GET /pprof/       pprof index
+//	GET /pprof/:name  pprof profiles, such as heap, goroutine, profile or trace
+//	GET /vars         expvar variables
+//	GET /stats        runtime stats JSON
+package debug
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"goblog"
+)
+
+type Options struct {
+	// Auth guards the routes, such as auth.Basic, optional but recommended,
+	// required with Force.
+	Auth goblog.Middleware
+	// Force enables the routes in production, it requires Auth.
+	Force bool
+}
+
+var start = time.Now()
+
+// Mount defines the debug routes on the router.
+func Mount(router *goblog.Router, options ...Options) {
+	opts := Options{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Force && opts.Auth == nil {
+		panic(goblog.Err.WithMsg("debug: Options.Auth required with Force"))
+	}
+
+	guard := []goblog.Middleware{enabled(opts.Force)}
+	if opts.Auth != nil {
+		guard = append(guard, opts.Auth)
+	}
+	get := func(pattern string, handler goblog.Middleware) {
+		router.Get(pattern, append(guard[:len(guard):len(guard)], handler)...)
+	}
+
+	get("/pprof/", goblog.WrapHandler(http.HandlerFunc(pprof.Index)))
+	get("/pprof/:name", profile)
+	get("/vars", goblog.WrapHandler(expvar.Handler()))
+	get("/stats", Stats)
+}
+
+func enabled(force bool) goblog.Middleware {
+	return func(ctx *goblog.Context) error {
+		if !force && ctx.Setting(goblog.SetEnv) == "production" {
+			return goblog.ErrNotFound.WithMsgf(`"%s" not found`, ctx.Path)
+		}
+		return nil
+	}
+}
+
+func profile(ctx *goblog.Context) error {
+	var handler http.Handler
+	switch name := ctx.Param("name"); name {
+	case "cmdline":
+		handler = http.HandlerFunc(pprof.Cmdline)
+	case "symbol":
+		handler = http.HandlerFunc(pprof.Symbol)
+	case "profile", "trace":
+		// CPU profile and trace last for the "seconds" query
+		ctx.SetDeadline(time.Time{})
+		handler = http.HandlerFunc(pprof.Profile)
+		if name == "trace" {
+			handler = http.HandlerFunc(pprof.Trace)
+		}
+	default:
+		handler = pprof.Handler(name)
+	}
+	return goblog.WrapHandler(handler)(ctx)
+}
+
+// RuntimeStats is the runtime stats JSON.
+type RuntimeStats struct {
+	GoVersion     string  `json:"goVersion"`
+	GOOS          string  `json:"goos"`
+	GOARCH        string  `json:"goarch"`
+	NumCPU        int     `json:"numCPU"`
+	GOMAXPROCS    int     `json:"gomaxprocs"`
+	NumGoroutine  int     `json:"numGoroutine"`
+	NumCgoCall    int64   `json:"numCgoCall"`
+	UptimeSeconds float64 `json:"uptimeSeconds"`
+	Memory        struct {
+		Alloc        uint64 `json:"alloc"`
+		TotalAlloc   uint64 `json:"totalAlloc"`
+		Sys          uint64 `json:"sys"`
+		Mallocs      uint64 `json:"mallocs"`
+		Frees        uint64 `json:"frees"`
+		HeapAlloc    uint64 `json:"heapAlloc"`
+		HeapSys      uint64 `json:"heapSys"`
+		HeapIdle     uint64 `json:"heapIdle"`
+		HeapInuse    uint64 `json:"heapInuse"`
+		HeapReleased uint64 `json:"heapReleased"`
+		HeapObjects  uint64 `json:"heapObjects"`
+		StackInuse   uint64 `json:"stackInuse"`
+	} `json:"memory"`
+	GC struct {
+		NumGC         uint32    `json:"numGC"`
+		NumForcedGC   uint32    `json:"numForcedGC"`
+		NextGC        uint64    `json:"nextGC"`
+		LastGC        time.Time `json:"lastGC"`
+		PauseTotalNs  uint64    `json:"pauseTotalNs"`
+		LastPauseNs   uint64    `json:"lastPauseNs"`
+		GCCPUFraction float64   `json:"gcCPUFraction"`
+	} `json:"gc"`
+}
+
+// ReadRuntimeStats reads the current runtime stats, it stops the world
+// briefly for runtime.ReadMemStats.
+func ReadRuntimeStats() *RuntimeStats {
+	m := new(runtime.MemStats)
+	runtime.ReadMemStats(m)
+
+	s := &RuntimeStats{
+		GoVersion:     runtime.Version(),
+		GOOS:          runtime.GOOS,
+		GOARCH:        runtime.GOARCH,
+		NumCPU:        runtime.NumCPU(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+		NumGoroutine:  runtime.NumGoroutine(),
+		NumCgoCall:    runtime.NumCgoCall(),
+		UptimeSeconds: time.Since(start).Seconds(),
+	}
+	s.Memory.Alloc = m.Alloc
+	s.Memory.TotalAlloc = m.TotalAlloc
+	s.Memory.Sys = m.Sys
+	s.Memory.Mallocs = m.Mallocs
+	s.Memory.Frees = m.Frees
+	s.Memory.HeapAlloc = m.HeapAlloc
+	s.Memory.HeapSys = m.HeapSys
+	s.Memory.HeapIdle = m.HeapIdle
+	s.Memory.HeapInuse = m.HeapInuse
+	s.Memory.HeapReleased = m.HeapReleased
+	s.Memory.HeapObjects = m.HeapObjects
+	s.Memory.StackInuse = m.StackInuse
+
+	s.GC.NumGC = m.NumGC
+	s.GC.NumForcedGC = m.NumForcedGC
+	s.GC.NextGC = m.NextGC
+	s.GC.PauseTotalNs = m.PauseTotalNs
+	s.GC.GCCPUFraction = m.GCCPUFraction
+	if m.NumGC > 0 {
+		s.GC.LastGC = time.Unix(0, int64(m.LastGC))
+		s.GC.LastPauseNs = m.PauseNs[(m.NumGC+255)%256]
+	}
+	return s
+}
+
+// Stats responds the runtime stats JSON, it can be mounted alone.
+func Stats(ctx *goblog.Context) error {
+	ctx.Set(goblog.HeaderCacheControl, "no-store")
+	return ctx.JSON(http.StatusOK, ReadRuntimeStats())
+}
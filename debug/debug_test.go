@@ -0,0 +1,84 @@
+package debug
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"goblog"
+)
+
+func TestStats(t *testing.T) {
+	app := goblog.New()
+	app.Use(enabled(false))
+	app.Use(Stats)
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/stats", nil))
+	stats := RuntimeStats{}
+	if err := json.Unmarshal(res.Body.Bytes(), &stats); err != nil || res.Code != 200 {
+		t.Fatalf("unexpected response %d %s", res.Code, res.Body.String())
+	}
+	if stats.GoVersion != runtime.Version() || stats.NumGoroutine == 0 || stats.Memory.Sys == 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	app.Set(goblog.SetEnv, "production")
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/stats", nil))
+	if res.Code != 404 {
+		t.Fatalf("expected 404 in production, got %d", res.Code)
+	}
+}
+
+func TestMount(t *testing.T) {
+	newApp := func(opts Options) *goblog.App {
+		router := goblog.NewRouter(goblog.RouterOptions{Root: "/debug"})
+		Mount(router, opts)
+		app := goblog.New()
+		app.UseHandler(router)
+		return app
+	}
+	request := func(app *goblog.App, path, token string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("X-Token", token)
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+		return res.Code
+	}
+
+	app := newApp(Options{})
+	app.Set(goblog.SetEnv, "development")
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/cmdline", "/debug/pprof/goroutine", "/debug/vars", "/debug/stats"} {
+		if code := request(app, path, ""); code != 200 {
+			t.Errorf("expected %s served in development, got %d", path, code)
+		}
+	}
+	app.Set(goblog.SetEnv, "production")
+	if code := request(app, "/debug/pprof/heap", ""); code != 404 {
+		t.Fatalf("expected 404 in production, got %d", code)
+	}
+
+	auth := func(ctx *goblog.Context) error {
+		if ctx.Get("X-Token") != "secret" {
+			return goblog.ErrUnauthorized
+		}
+		return nil
+	}
+	app = newApp(Options{Auth: auth, Force: true})
+	app.Set(goblog.SetEnv, "production")
+	if code := request(app, "/debug/pprof/heap", ""); code != 401 {
+		t.Fatalf("expected unauthenticated request rejected, got %d", code)
+	}
+	if code := request(app, "/debug/pprof/heap", "secret"); code != 200 {
+		t.Fatalf("expected forced routes in production, got %d", code)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected Force without Auth to panic")
+		}
+	}()
+	newApp(Options{Force: true})
+}
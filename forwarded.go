@@ -0,0 +1,187 @@
+package goblog
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// forwarded is one proxy hop, from an element of the RFC 7239 Forwarded
+// header or from the X-Forwarded-* headers.
+type forwarded struct {
+	ip    net.IP
+	proto string
+	host  string
+}
+
+func parseTrustedProxies(vals []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(vals))
+	for _, val := range vals {
+		if !strings.Contains(val, "/") {
+			ip := net.ParseIP(val)
+			if ip == nil {
+				return nil, &net.ParseError{Type: "IP address", Text: val}
+			}
+			bits := 8 * net.IPv6len
+			if v4 := ip.To4(); v4 != nil {
+				ip, bits = v4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(val)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func (app *App) isTrustedProxy(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range app.trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveForwarded returns the client information of the request. It walks
+// the proxy hops from the right, skipping trusted proxies, and stops at the
+// first untrusted hop: that is the client. Proxy headers are ignored entirely
+// unless the direct peer is a trusted proxy.
+func (app *App) resolveForwarded(r *http.Request) forwarded {
+	res := forwarded{proto: "http", host: r.Host}
+	if r.TLS != nil {
+		res.proto = "https"
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	res.ip = net.ParseIP(host)
+	if !app.isTrustedProxy(res.ip) {
+		return res
+	}
+
+	hops := forwardedHops(r.Header)
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := hops[i]
+		if hop.ip == nil {
+			// "unknown", obfuscated or malformed node, can't go any further
+			break
+		}
+		res.ip = hop.ip
+		if hop.proto != "" {
+			res.proto = hop.proto
+		}
+		if hop.host != "" {
+			res.host = hop.host
+		}
+		if !app.isTrustedProxy(hop.ip) {
+			break
+		}
+	}
+	return res
+}
+
+// forwardedHops reads the Forwarded header, falling back to X-Forwarded-For,
+// X-Forwarded-Proto, X-Forwarded-Host and X-Real-IP.
+func forwardedHops(header http.Header) []forwarded {
+	if vals := header.Values(HeaderForwarded); len(vals) > 0 {
+		return parseForwarded(strings.Join(vals, ","))
+	}
+
+	var hops []forwarded
+	if vals := header.Values(HeaderXForwardedFor); len(vals) > 0 {
+		for _, val := range splitList(strings.Join(vals, ",")) {
+			hops = append(hops, forwarded{ip: net.ParseIP(val)})
+		}
+	} else if val := header.Get(HeaderXRealIP); val != "" {
+		hops = append(hops, forwarded{ip: net.ParseIP(strings.TrimSpace(val))})
+	}
+	if len(hops) == 0 {
+		return nil
+	}
+
+	// X-Forwarded-Proto and X-Forwarded-Host line up with X-Forwarded-For when
+	// every proxy appends to them, otherwise they were set by the nearest one.
+	setHopValues(hops, header.Values(HeaderXForwardedProto), func(hop *forwarded, val string) {
+		hop.proto = strings.ToLower(val)
+	})
+	setHopValues(hops, header.Values(HeaderXForwardedHost), func(hop *forwarded, val string) {
+		hop.host = val
+	})
+	return hops
+}
+
+func setHopValues(hops []forwarded, vals []string, set func(*forwarded, string)) {
+	if len(vals) == 0 {
+		return
+	}
+	list := splitList(strings.Join(vals, ","))
+	if len(list) == len(hops) {
+		for i, val := range list {
+			set(&hops[i], val)
+		}
+	} else if len(list) > 0 {
+		set(&hops[len(hops)-1], list[len(list)-1])
+	}
+}
+
+// parseForwarded parses the RFC 7239 Forwarded header, such as:
+//
+//	Forwarded: for=192.0.2.60;proto=http;by=203.0.113.43, for="[2001:db8:cafe::17]:4711"
+func parseForwarded(val string) []forwarded {
+	var hops []forwarded
+	for _, elem := range splitList(val) {
+		var hop forwarded
+		for _, pair := range strings.Split(elem, ";") {
+			index := strings.IndexByte(pair, '=')
+			if index < 0 {
+				continue
+			}
+			key := strings.ToLower(strings.TrimSpace(pair[:index]))
+			value := strings.Trim(strings.TrimSpace(pair[index+1:]), `"`)
+			switch key {
+			case "for":
+				hop.ip = parseNodeIP(value)
+			case "proto":
+				hop.proto = strings.ToLower(value)
+			case "host":
+				hop.host = value
+			}
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// parseNodeIP parses a Forwarded node, such as "192.0.2.43", "192.0.2.43:47011",
+// "[2001:db8:cafe::17]:4711". It returns nil for "unknown" and obfuscated nodes.
+func parseNodeIP(node string) net.IP {
+	if strings.HasPrefix(node, "[") {
+		if index := strings.IndexByte(node, ']'); index > 0 {
+			node = node[1:index]
+		}
+	} else if host, _, err := net.SplitHostPort(node); err == nil {
+		node = host
+	}
+	return net.ParseIP(node)
+}
+
+func splitList(val string) []string {
+	list := strings.Split(val, ",")
+	res := list[:0]
+	for _, item := range list {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
@@ -0,0 +1,251 @@
+// Package health provides liveness and readiness endpoints with pluggable
+// checks, for Kubernetes probes and load balancers.
+//
+//	h := health.New()
+//	h.Readiness("db", func(c context.Context) error {
+//		return db.PingContext(c)
+//	}, health.CheckOptions{Timeout: time.Second, CacheTTL: 5 * time.Second})
+//	h.Mount(app)
+//
+// GET /livez runs the liveness checks, GET /readyz the readiness checks and
+// GET /healthz all of them. They respond a JSON Report, with status 200 if all
+// checks pass, or 503. Readiness fails as soon as the App graceful shutdown
+// (App.Close) starts.
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"goblog"
+)
+
+// Check statuses.
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// CheckFunc reports the health of a component, it should respect the
+// context deadline.
+type CheckFunc func(c context.Context) error
+
+type CheckOptions struct {
+	// Timeout of the check, default to 5 seconds.
+	Timeout time.Duration
+	// CacheTTL caches the result of expensive checks, not cached by default.
+	CacheTTL time.Duration
+}
+
+type Options struct {
+	// LivePath, ReadyPath and HealthPath default to "/livez", "/readyz" and
+	// "/healthz".
+	LivePath   string
+	ReadyPath  string
+	HealthPath string
+}
+
+// Result is the result of a check.
+type Result struct {
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs float64   `json:"durationMs"`
+	CheckedAt  time.Time `json:"checkedAt"`
+	Cached     bool      `json:"cached,omitempty"`
+}
+
+// Report aggregates the results of the checks.
+type Report struct {
+	Status string            `json:"status"`
+	Reason string            `json:"reason,omitempty"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+type check struct {
+	name     string
+	fn       CheckFunc
+	timeout  time.Duration
+	cacheTTL time.Duration
+	live     bool
+
+	mu     sync.Mutex // serializes the runs, concurrent probes share the result
+	result Result
+}
+
+func (c *check) run(ctx context.Context) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cacheTTL > 0 && !c.result.CheckedAt.IsZero() && time.Since(c.result.CheckedAt) < c.cacheTTL {
+		res := c.result
+		res.Cached = true
+		return res
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				errCh <- goblog.ErrorWithStack(err)
+			}
+		}()
+		errCh <- c.fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	res := Result{Status: StatusOK, CheckedAt: start}
+	res.DurationMs = float64(time.Since(start)) / float64(time.Millisecond)
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+	c.result = res
+	return res
+}
+
+type Health struct {
+	opts     Options
+	mu       sync.RWMutex
+	checks   []*check
+	names    map[string]bool
+	shutdown int32
+}
+
+func New(options ...Options) *Health {
+	opts := Options{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.LivePath == "" {
+		opts.LivePath = "/livez"
+	}
+	if opts.ReadyPath == "" {
+		opts.ReadyPath = "/readyz"
+	}
+	if opts.HealthPath == "" {
+		opts.HealthPath = "/healthz"
+	}
+	return &Health{opts: opts, names: make(map[string]bool)}
+}
+
+// Liveness registers a check telling if the process should be restarted,
+// such as a deadlock detector. Most checks are readiness checks.
+func (h *Health) Liveness(name string, fn CheckFunc, options ...CheckOptions) {
+	h.add(name, fn, true, options)
+}
+
+// Readiness registers a check telling if the process can serve traffic, such
+// as a database ping.
+func (h *Health) Readiness(name string, fn CheckFunc, options ...CheckOptions) {
+	h.add(name, fn, false, options)
+}
+
+func (h *Health) add(name string, fn CheckFunc, live bool, options []CheckOptions) {
+	opts := CheckOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if name == "" || h.names[name] {
+		panic(goblog.Err.WithMsgf("health: invalid or duplicate check name %q", name))
+	}
+	h.names[name] = true
+	h.checks = append(h.checks, &check{name: name, fn: fn, timeout: opts.Timeout, cacheTTL: opts.CacheTTL, live: live})
+}
+
+// Shutdown makes readiness fail, it is called when the graceful shutdown of
+// the mounted App starts. It can be called earlier to drain the traffic.
+func (h *Health) Shutdown() {
+	atomic.StoreInt32(&h.shutdown, 1)
+}
+
+// Mount uses the Health on the app, and makes readiness fail when the app
+// graceful shutdown starts.
+func (h *Health) Mount(app *goblog.App) {
+	app.UseHandler(h)
+	app.Server.RegisterOnShutdown(h.Shutdown)
+}
+
+// Check runs the checks concurrently, all of them, only liveness or only
+// readiness ones, and aggregates the results.
+func (h *Health) Check(parent context.Context, live, ready bool) *Report {
+	report := &Report{Status: StatusOK, Checks: make(map[string]Result)}
+	if ready && !live && atomic.LoadInt32(&h.shutdown) == 1 {
+		report.Status = StatusFail
+		report.Reason = "shutting down"
+		return report
+	}
+
+	h.mu.RLock()
+	checks := make([]*check, 0, len(h.checks))
+	for _, c := range h.checks {
+		if (c.live && live) || (!c.live && ready) {
+			checks = append(checks, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	wg := sync.WaitGroup{}
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			results[i] = c.run(parent)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// Serve implements goblog.Handler interface, it responds the reports on the
+// configured paths.
+func (h *Health) Serve(ctx *goblog.Context) error {
+	if ctx.Method != http.MethodGet && ctx.Method != http.MethodHead {
+		return nil
+	}
+
+	var report *Report
+	switch ctx.Path {
+	case h.opts.LivePath:
+		report = h.Check(ctx.Context(), true, false)
+	case h.opts.ReadyPath:
+		report = h.Check(ctx.Context(), false, true)
+	case h.opts.HealthPath:
+		report = h.Check(ctx.Context(), true, true)
+	default:
+		return nil
+	}
+
+	code := http.StatusOK
+	if report.Status != StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	ctx.Set(goblog.HeaderCacheControl, "no-store")
+	return ctx.JSON(code, report)
+}
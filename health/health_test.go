@@ -0,0 +1,66 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"goblog"
+)
+
+func TestHealth(t *testing.T) {
+	calls := 0
+	h := New()
+	h.Liveness("loop", func(c context.Context) error { return nil })
+	h.Readiness("db", func(c context.Context) error {
+		calls++
+		return errors.New("connection refused")
+	}, CheckOptions{CacheTTL: time.Minute})
+	h.Readiness("slow", func(c context.Context) error {
+		<-c.Done()
+		return c.Err()
+	}, CheckOptions{Timeout: 10 * time.Millisecond})
+
+	app := goblog.New()
+	h.Mount(app)
+
+	request := func(path string) (int, Report) {
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, httptest.NewRequest("GET", path, nil))
+		report := Report{}
+		json.Unmarshal(res.Body.Bytes(), &report)
+		return res.Code, report
+	}
+
+	if code, report := request("/livez"); code != 200 || len(report.Checks) != 1 {
+		t.Fatalf("expected live, got %d %+v", code, report)
+	}
+	code, report := request("/readyz")
+	if code != 503 || report.Checks["db"].Error != "connection refused" ||
+		report.Checks["slow"].Error != context.DeadlineExceeded.Error() {
+		t.Fatalf("expected not ready, got %d %+v", code, report)
+	}
+	if _, report = request("/healthz"); len(report.Checks) != 3 || !report.Checks["db"].Cached || calls != 1 {
+		t.Fatalf("expected cached db check, got %+v", report)
+	}
+
+	app.Close()
+	// RegisterOnShutdown hooks run asynchronously
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		if code, report = request("/readyz"); report.Reason == "shutting down" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected shutting down, got %d %+v", code, report)
+		}
+	}
+	if code != 503 {
+		t.Fatalf("expected not ready during shutdown, got %d", code)
+	}
+	if code, _ = request("/livez"); code != 200 {
+		t.Fatalf("expected live during shutdown, got %d", code)
+	}
+}
@@ -0,0 +1,119 @@
+package goblog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MessageCatalog holds error messages by locale and error code, the AppCode
+// such as "POST_NOT_FOUND", or the status code such as "404". Messages can
+// have "{name}" parameters, filled from the Error.Data map.
+//
+//	messages := goblog.NewMessageCatalog("en")
+//	messages.Add("en", map[string]string{"POST_NOT_FOUND": "post {id} not found"})
+//	messages.Add("zh", map[string]string{"POST_NOT_FOUND": "文章 {id} 不存在"})
+//	app.Set(goblog.SetMessageCatalog, messages)
+type MessageCatalog struct {
+	mu       sync.RWMutex
+	fallback string
+	locales  []string
+	messages map[string]map[string]string
+}
+
+// NewMessageCatalog creates a catalog, the fallback locale is used when no
+// locale of the catalog is acceptable.
+func NewMessageCatalog(fallback string) *MessageCatalog {
+	return &MessageCatalog{fallback: fallback, messages: make(map[string]map[string]string)}
+}
+
+// Add adds the messages of the locale, by error code.
+func (c *MessageCatalog) Add(locale string, messages map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m, ok := c.messages[locale]
+	if !ok {
+		m = make(map[string]string, len(messages))
+		c.messages[locale] = m
+		c.locales = append(c.locales, locale)
+	}
+	for code, msg := range messages {
+		m[code] = msg
+	}
+}
+
+// Locales returns the locales of the catalog, in the order they were added.
+func (c *MessageCatalog) Locales() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.locales...)
+}
+
+// Message returns the message of the code in the locale, or in the fallback
+// locale, with the parameters interpolated.
+func (c *MessageCatalog) Message(locale, code string, params map[string]interface{}) (string, bool) {
+	c.mu.RLock()
+	msg, ok := c.messages[locale][code]
+	if !ok {
+		msg, ok = c.messages[c.fallback][code]
+	}
+	c.mu.RUnlock()
+	if !ok {
+		return "", false
+	}
+	return interpolate(msg, params), true
+}
+
+// Localize returns a copy of the error with the message in the locale, or
+// the error itself if the catalog has no message of it.
+func (c *MessageCatalog) Localize(err *Error, locale string) *Error {
+	code := err.AppCode
+	if code == "" {
+		code = strconv.Itoa(err.Code)
+	}
+	msg, ok := c.Message(locale, code, errorParams(err.Data))
+	if !ok {
+		return err
+	}
+	return err.WithMsg(msg)
+}
+
+func interpolate(msg string, params map[string]interface{}) string {
+	if len(params) == 0 || !strings.Contains(msg, "{") {
+		return msg
+	}
+	pairs := make([]string, 0, 2*len(params))
+	for key, val := range params {
+		pairs = append(pairs, "{"+key+"}", fmt.Sprint(val))
+	}
+	return strings.NewReplacer(pairs...).Replace(msg)
+}
+
+func errorParams(data interface{}) map[string]interface{} {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		return v
+	case map[string]string:
+		params := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			params[key] = val
+		}
+		return params
+	}
+	return nil
+}
+
+// localizeError localizes the error by the Accept-Language of the request.
+func (ctx *Context) localizeError(err HTTPError) HTTPError {
+	e, ok := err.(*Error)
+	if !ok {
+		return err
+	}
+	locale := ctx.AcceptLanguage(ctx.app.messages.Locales()...)
+	if locale == "" {
+		locale = ctx.app.messages.fallback
+	}
+	ctx.Res.Vary(HeaderAcceptLanguage)
+	return ctx.app.messages.Localize(e, locale)
+}
@@ -0,0 +1,101 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Claims is the JWT payload. Registered claims have typed accessors.
+type Claims map[string]interface{}
+
+func (c Claims) Issuer() string {
+	s, _ := c["iss"].(string)
+	return s
+}
+
+func (c Claims) Subject() string {
+	s, _ := c["sub"].(string)
+	return s
+}
+
+// Audience returns the "aud" claim, which may be a string or an array.
+func (c Claims) Audience() []string {
+	switch v := c["aud"].(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		aud := make([]string, 0, len(v))
+		for _, val := range v {
+			if s, ok := val.(string); ok {
+				aud = append(aud, s)
+			}
+		}
+		return aud
+	}
+	return nil
+}
+
+func (c Claims) ExpiresAt() (time.Time, bool) {
+	return c.time("exp")
+}
+
+func (c Claims) NotBefore() (time.Time, bool) {
+	return c.time("nbf")
+}
+
+func (c Claims) IssuedAt() (time.Time, bool) {
+	return c.time("iat")
+}
+
+func (c Claims) time(name string) (time.Time, bool) {
+	var sec float64
+	switch v := c[name].(type) {
+	case float64:
+		sec = v
+	case int64:
+		sec = float64(v)
+	case int:
+		sec = float64(v)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, false
+		}
+		sec = f
+	case time.Time:
+		return v, true
+	default:
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(sec*float64(time.Second))), true
+}
+
+// validate checks the time based claims, and the "iss", "aud" claims when
+// the options require them.
+func (c Claims) validate(opts *Options, now time.Time) error {
+	if exp, ok := c.ExpiresAt(); ok && !now.Before(exp.Add(opts.Leeway)) {
+		return errors.New("token expired")
+	} else if !ok && c["exp"] != nil {
+		return errors.New(`invalid "exp" claim`)
+	}
+	if nbf, ok := c.NotBefore(); ok && now.Add(opts.Leeway).Before(nbf) {
+		return errors.New("token not valid yet")
+	} else if !ok && c["nbf"] != nil {
+		return errors.New(`invalid "nbf" claim`)
+	}
+	if opts.Issuer != "" && c.Issuer() != opts.Issuer {
+		return errors.New("invalid token issuer")
+	}
+	if opts.Audience != "" {
+		for _, aud := range c.Audience() {
+			if aud == opts.Audience {
+				return nil
+			}
+		}
+		return errors.New("invalid token audience")
+	}
+	return nil
+}
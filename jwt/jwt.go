@@ -0,0 +1,156 @@
+// Package jwt signs and verifies JSON Web Tokens with HS256, RS256 and ES256,
+// and provides a Bearer authentication middleware populating the claims.
+//
+//	keys := []string{"new secret", "old secret"}
+//	app.Set(goblog.SetKeys, keys)
+//	j := jwt.New(jwt.Options{KeySet: jwt.NewHMACKeySet(keys...), Issuer: "goblog"})
+//
+//	router.Get("/api/user", j.Serve, func(ctx *goblog.Context) error {
+//		return ctx.JSON(200, jwt.FromCtx(ctx))
+//	})
+package jwt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"goblog"
+	"goblog/auth"
+)
+
+var b64 = base64.RawURLEncoding
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+type Options struct {
+	// KeySet is required. To share the app SetKeys list, pass the same keys
+	// to NewHMACKeySet, the middleware doesn't read the app settings.
+	KeySet *KeySet
+	// ExpiresIn sets the "exp" claim of signed tokens if absent, optional.
+	ExpiresIn time.Duration
+	// Issuer is set on signed tokens and required on verified tokens, optional.
+	Issuer string
+	// Audience is required on verified tokens, optional.
+	Audience string
+	// Leeway tolerates clock skew when validating "exp" and "nbf".
+	Leeway time.Duration
+	// Realm is the protection space sent in WWW-Authenticate, optional.
+	Realm string
+}
+
+type JWT struct {
+	opts   Options
+	bearer goblog.Middleware
+}
+
+func New(opts Options) *JWT {
+	if opts.KeySet == nil {
+		panic(goblog.Err.WithMsg("jwt: Options.KeySet required"))
+	}
+	j := &JWT{opts: opts}
+	j.bearer = auth.Bearer(auth.BearerOptions{
+		Realm: opts.Realm,
+		Verifier: func(_ *goblog.Context, token string) (interface{}, error) {
+			return j.Verify(token)
+		},
+	})
+	return j
+}
+
+// Sign returns a token of the claims, signed by the signing key of the KeySet.
+func (j *JWT) Sign(claims Claims) (string, error) {
+	key := j.opts.KeySet.signingKey()
+	if key == nil {
+		return "", errors.New("jwt: no signing key")
+	}
+
+	payload := make(Claims, len(claims)+3)
+	for k, v := range claims {
+		payload[k] = v
+	}
+	now := time.Now()
+	if _, ok := payload["iat"]; !ok {
+		payload["iat"] = now.Unix()
+	}
+	if _, ok := payload["exp"]; !ok && j.opts.ExpiresIn > 0 {
+		payload["exp"] = now.Add(j.opts.ExpiresIn).Unix()
+	}
+	if _, ok := payload["iss"]; !ok && j.opts.Issuer != "" {
+		payload["iss"] = j.opts.Issuer
+	}
+
+	h, err := json.Marshal(header{Alg: key.Alg, Typ: "JWT", Kid: key.ID})
+	if err != nil {
+		return "", err
+	}
+	p, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	data := b64.EncodeToString(h) + "." + b64.EncodeToString(p)
+	sig, err := key.sign([]byte(data))
+	if err != nil {
+		return "", err
+	}
+	return data + "." + b64.EncodeToString(sig), nil
+}
+
+// Verify verifies the token signature and validates its claims. The error
+// is a 401 *goblog.Error.
+func (j *JWT) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, goblog.ErrUnauthorized.WithMsg("malformed token")
+	}
+
+	var h header
+	buf, err := b64.DecodeString(parts[0])
+	if err == nil {
+		err = json.Unmarshal(buf, &h)
+	}
+	if err != nil {
+		return nil, goblog.ErrUnauthorized.WithMsg("malformed token header")
+	}
+	sig, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return nil, goblog.ErrUnauthorized.WithMsg("malformed token signature")
+	}
+	if err = j.opts.KeySet.verify(h.Alg, h.Kid, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, goblog.ErrUnauthorized.WithMsg(err.Error())
+	}
+
+	claims := Claims{}
+	buf, err = b64.DecodeString(parts[1])
+	if err == nil {
+		dec := json.NewDecoder(bytes.NewReader(buf))
+		dec.UseNumber()
+		err = dec.Decode(&claims)
+	}
+	if err != nil {
+		return nil, goblog.ErrUnauthorized.WithMsg("malformed token claims")
+	}
+	if err = claims.validate(&j.opts, time.Now()); err != nil {
+		return nil, goblog.ErrUnauthorized.WithMsg(err.Error())
+	}
+	return claims, nil
+}
+
+// Serve implements goblog.Handler interface. It verifies the Bearer token
+// and stores the claims, retrievable by FromCtx.
+func (j *JWT) Serve(ctx *goblog.Context) error {
+	return j.bearer(ctx)
+}
+
+// FromCtx returns the claims verified by the middleware, or nil.
+func FromCtx(ctx *goblog.Context) Claims {
+	claims, _ := auth.FromCtx(ctx).(Claims)
+	return claims
+}
@@ -0,0 +1,61 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+)
+
+func TestJWT(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	for _, key := range []*Key{HMACKey("h", []byte("secret")), RSAKey("r", rsaKey), ECDSAKey("e", ecKey)} {
+		j := New(Options{KeySet: NewKeySet(key), Issuer: "goblog", Audience: "api", ExpiresIn: time.Minute})
+		token, err := j.Sign(Claims{"sub": "user", "aud": "api"})
+		if err != nil {
+			t.Fatalf("%s: %s", key.Alg, err)
+		}
+		claims, err := j.Verify(token)
+		if err != nil {
+			t.Fatalf("%s: %s", key.Alg, err)
+		}
+		if claims.Subject() != "user" || claims.Issuer() != "goblog" {
+			t.Fatalf("%s: unexpected claims %v", key.Alg, claims)
+		}
+		if _, err = j.Verify(token[:len(token)-2] + "xx"); err == nil {
+			t.Fatalf("%s: expected invalid signature", key.Alg)
+		}
+	}
+}
+
+func TestJWT_Claims(t *testing.T) {
+	j := New(Options{KeySet: NewHMACKeySet("secret"), Leeway: time.Minute})
+
+	expired, _ := j.Sign(Claims{"exp": time.Now().Add(-2 * time.Minute).Unix()})
+	if _, err := j.Verify(expired); err == nil {
+		t.Fatal("expected expired token")
+	}
+	skewed, _ := j.Sign(Claims{"exp": time.Now().Add(-30 * time.Second).Unix()})
+	if _, err := j.Verify(skewed); err != nil {
+		t.Fatalf("expected token within leeway, got %s", err)
+	}
+}
+
+func TestKeySet_Rotate(t *testing.T) {
+	ks := NewKeySet(HMACKey("1", []byte("old")))
+	j := New(Options{KeySet: ks})
+	old, _ := j.Sign(Claims{})
+
+	ks.Rotate(HMACKey("2", []byte("new")))
+	if _, err := j.Verify(old); err != nil {
+		t.Fatalf("expected old token still valid, got %s", err)
+	}
+	ks.Remove("1")
+	if _, err := j.Verify(old); err == nil {
+		t.Fatal("expected old token invalid after removing its key")
+	}
+}
@@ -0,0 +1,196 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"math/big"
+	"sync"
+
+	"goblog"
+)
+
+// Supported algorithms.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+var errInvalidSignature = errors.New("invalid signature")
+
+// Key is a signing or verifying key of an algorithm. Keys created from
+// public keys can only verify tokens.
+type Key struct {
+	ID  string
+	Alg string
+
+	secret []byte
+	rsaKey *rsa.PrivateKey
+	rsaPub *rsa.PublicKey
+	ecKey  *ecdsa.PrivateKey
+	ecPub  *ecdsa.PublicKey
+}
+
+func HMACKey(id string, secret []byte) *Key {
+	return &Key{ID: id, Alg: HS256, secret: secret}
+}
+
+func RSAKey(id string, key *rsa.PrivateKey) *Key {
+	return &Key{ID: id, Alg: RS256, rsaKey: key, rsaPub: &key.PublicKey}
+}
+
+func RSAPublicKey(id string, key *rsa.PublicKey) *Key {
+	return &Key{ID: id, Alg: RS256, rsaPub: key}
+}
+
+// ECDSAKey returns a ES256 key, the curve must be P-256.
+func ECDSAKey(id string, key *ecdsa.PrivateKey) *Key {
+	checkCurve(key.Curve)
+	return &Key{ID: id, Alg: ES256, ecKey: key, ecPub: &key.PublicKey}
+}
+
+// ECDSAPublicKey returns a ES256 verifying key, the curve must be P-256.
+func ECDSAPublicKey(id string, key *ecdsa.PublicKey) *Key {
+	checkCurve(key.Curve)
+	return &Key{ID: id, Alg: ES256, ecPub: key}
+}
+
+func checkCurve(curve elliptic.Curve) {
+	if curve != elliptic.P256() {
+		panic(goblog.Err.WithMsg("jwt: ES256 requires a P-256 key"))
+	}
+}
+
+func (k *Key) canSign() bool {
+	return k.secret != nil || k.rsaKey != nil || k.ecKey != nil
+}
+
+func (k *Key) sign(data []byte) ([]byte, error) {
+	switch {
+	case k.secret != nil:
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(data)
+		return mac.Sum(nil), nil
+	case k.rsaKey != nil:
+		sum := sha256.Sum256(data)
+		return rsa.SignPKCS1v15(rand.Reader, k.rsaKey, crypto.SHA256, sum[:])
+	case k.ecKey != nil:
+		sum := sha256.Sum256(data)
+		r, s, err := ecdsa.Sign(rand.Reader, k.ecKey, sum[:])
+		if err != nil {
+			return nil, err
+		}
+		// fixed-width R || S, RFC 7518 section 3.4
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return sig, nil
+	}
+	return nil, errors.New("key can't sign")
+}
+
+func (k *Key) verify(data, sig []byte) error {
+	switch {
+	case k.secret != nil:
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(data)
+		if subtle.ConstantTimeCompare(mac.Sum(nil), sig) != 1 {
+			return errInvalidSignature
+		}
+		return nil
+	case k.rsaPub != nil:
+		sum := sha256.Sum256(data)
+		if rsa.VerifyPKCS1v15(k.rsaPub, crypto.SHA256, sum[:], sig) != nil {
+			return errInvalidSignature
+		}
+		return nil
+	case k.ecPub != nil:
+		if len(sig) != 64 {
+			return errInvalidSignature
+		}
+		sum := sha256.Sum256(data)
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(k.ecPub, sum[:], r, s) {
+			return errInvalidSignature
+		}
+		return nil
+	}
+	return errInvalidSignature
+}
+
+// KeySet is a list of keys supporting rotation: the first key able to sign
+// signs new tokens, every key verifies tokens. Tokens carrying a "kid"
+// header are verified by that key only.
+type KeySet struct {
+	mu   sync.RWMutex
+	keys []*Key
+}
+
+func NewKeySet(keys ...*Key) *KeySet {
+	return &KeySet{keys: keys}
+}
+
+// NewHMACKeySet returns a HS256 KeySet of the keys. The first key signs, all
+// of them verify. The app SetKeys list is not read, pass the same keys to
+// share them, and rotate both together.
+func NewHMACKeySet(keys ...string) *KeySet {
+	ks := &KeySet{}
+	for _, key := range keys {
+		ks.keys = append(ks.keys, HMACKey("", []byte(key)))
+	}
+	return ks
+}
+
+// Rotate makes key the signing key, previous keys are kept for verifying
+// the tokens they signed.
+func (ks *KeySet) Rotate(key *Key) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys = append([]*Key{key}, ks.keys...)
+}
+
+// Remove removes the keys with the id.
+func (ks *KeySet) Remove(id string) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	keys := make([]*Key, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		if key.ID != id {
+			keys = append(keys, key)
+		}
+	}
+	ks.keys = keys
+}
+
+func (ks *KeySet) signingKey() *Key {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	for _, key := range ks.keys {
+		if key.canSign() {
+			return key
+		}
+	}
+	return nil
+}
+
+func (ks *KeySet) verify(alg, kid string, data, sig []byte) error {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	for _, key := range ks.keys {
+		if key.Alg != alg || (kid != "" && key.ID != kid) {
+			continue
+		}
+		if key.verify(data, sig) == nil {
+			return nil
+		}
+	}
+	return errInvalidSignature
+}
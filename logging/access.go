@@ -0,0 +1,157 @@
+package logging
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"goblog"
+)
+
+// Access log formats for SetAccessFormat.
+const (
+	// CommonFormat is the Apache Common Log Format.
+	CommonFormat = `:remote-addr - :remote-user [:date[clf]] ":method :url HTTP/:http-version" :status :res[content-length]`
+	// CombinedFormat is the Apache Combined Log Format.
+	CombinedFormat = CommonFormat + ` ":referrer" ":user-agent"`
+	// DevFormat is a concise format for development.
+	DevFormat = `:method :url :status :response-time ms - :res[content-length]`
+)
+
+type accessToken func(log Log, ctx *goblog.Context, end time.Time, arg string) string
+
+// accessTokens are the tokens of access log formats, a missing value is "-".
+var accessTokens = map[string]accessToken{
+	"remote-addr": func(log Log, ctx *goblog.Context, _ time.Time, _ string) string {
+		if ip := ctx.IP(); ip != nil {
+			return ip.String()
+		}
+		return ""
+	},
+	"remote-user": func(log Log, ctx *goblog.Context, _ time.Time, _ string) string {
+		user, _, _ := ctx.Req.BasicAuth()
+		return user
+	},
+	"date": func(log Log, ctx *goblog.Context, end time.Time, arg string) string {
+		switch arg {
+		case "iso":
+			return end.UTC().Format(time.RFC3339)
+		case "web":
+			return end.UTC().Format(time.RFC1123)
+		default:
+			return end.Format("02/Jan/2006:15:04:05 -0700")
+		}
+	},
+	"method": func(log Log, ctx *goblog.Context, _ time.Time, _ string) string {
+		return ctx.Method
+	},
+	"url": func(log Log, ctx *goblog.Context, _ time.Time, _ string) string {
+		return ctx.Req.URL.RequestURI()
+	},
+	"http-version": func(log Log, ctx *goblog.Context, _ time.Time, _ string) string {
+		return strconv.Itoa(ctx.Req.ProtoMajor) + "." + strconv.Itoa(ctx.Req.ProtoMinor)
+	},
+	"status": func(log Log, ctx *goblog.Context, _ time.Time, _ string) string {
+		return strconv.Itoa(ctx.Res.Status())
+	},
+	"response-time": func(log Log, ctx *goblog.Context, end time.Time, _ string) string {
+		if start, ok := log["Start"].(time.Time); ok {
+			return strconv.FormatFloat(float64(end.Sub(start))/1e6, 'f', 3, 64)
+		}
+		return ""
+	},
+	"referrer": func(log Log, ctx *goblog.Context, _ time.Time, _ string) string {
+		return ctx.Get(goblog.HeaderReferer)
+	},
+	"user-agent": func(log Log, ctx *goblog.Context, _ time.Time, _ string) string {
+		return ctx.Get(goblog.HeaderUserAgent)
+	},
+	"route": func(log Log, ctx *goblog.Context, _ time.Time, _ string) string {
+		return ctx.RoutePattern()
+	},
+	"request-id": func(log Log, ctx *goblog.Context, _ time.Time, _ string) string {
+		id, _ := log["RequestID"].(string)
+		return id
+	},
+	"req": func(log Log, ctx *goblog.Context, _ time.Time, arg string) string {
+		return ctx.Get(arg)
+	},
+	"res": func(log Log, ctx *goblog.Context, _ time.Time, arg string) string {
+		val := ctx.Res.Get(arg)
+		if val == "" && strings.EqualFold(arg, goblog.HeaderContentLength) {
+			if n := len(ctx.Res.Body()); n > 0 {
+				val = strconv.Itoa(n)
+			}
+		}
+		return val
+	},
+}
+
+var accessTokenReg = regexp.MustCompile(`:([a-z][a-z0-9-]*)(?:\[([^\]]+)\])?`)
+
+type accessPart struct {
+	text  string
+	token accessToken
+	arg   string
+}
+
+func compileAccessFormat(format string) []accessPart {
+	parts := make([]accessPart, 0)
+	last := 0
+	for _, m := range accessTokenReg.FindAllStringSubmatchIndex(format, -1) {
+		name := format[m[2]:m[3]]
+		token, ok := accessTokens[name]
+		if !ok {
+			panic(goblog.Err.WithMsgf("logging: unknown access log token :%s", name))
+		}
+		arg := ""
+		if m[4] >= 0 {
+			arg = format[m[4]:m[5]]
+		}
+		if (name == "req" || name == "res") && arg == "" {
+			panic(goblog.Err.WithMsgf("logging: access log token :%s requires a header name", name))
+		}
+		parts = append(parts, accessPart{text: format[last:m[0]]}, accessPart{token: token, arg: arg})
+		last = m[1]
+	}
+	return append(parts, accessPart{text: format[last:]})
+}
+
+// SetAccessFormat logs the requests in an access log format, CommonFormat,
+// CombinedFormat, DevFormat or a custom format of tokens, such as
+// ":method :url :status :response-time ms :req[Accept] :res[Content-Type] :route".
+// Access lines are written at the InfoLevel, as is, or as the message of the
+// Formatter if set, such as SyslogFormatter.
+func (l *Logger) SetAccessFormat(format string) {
+	parts := compileAccessFormat(format)
+	l.SetLogConsume(func(log Log, ctx *goblog.Context) {
+		r := l.root()
+		if !r.Enabled(InfoLevel) {
+			return
+		}
+		end := time.Now()
+		buf := new(bytes.Buffer)
+		for _, part := range parts {
+			if part.token == nil {
+				buf.WriteString(part.text)
+			} else if val := part.token(log, ctx, end, part.arg); val != "" {
+				buf.WriteString(crlfEscaper.Replace(val))
+			} else {
+				buf.WriteByte('-')
+			}
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		line := buf.Bytes()
+		if r.formatter != nil {
+			var err error
+			if line, err = r.formatter.Format(end, InfoLevel, buf.String(), nil); err != nil {
+				return
+			}
+		}
+		r.emit(InfoLevel, append(line, '\n'))
+	})
+}
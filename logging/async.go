@@ -0,0 +1,136 @@
+package logging
+
+import (
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+type AsyncOptions struct {
+	// Size is the max number of buffered writes, default to 1024.
+	Size int
+	// Block blocks the writes when the buffer is full, instead of dropping
+	// them.
+	Block bool
+}
+
+type asyncItem struct {
+	buf  []byte
+	done chan struct{} // flush marker
+}
+
+// AsyncWriter writes to the underlying writer in a goroutine, so that slow
+// disks don't stall the requests. Writes are buffered up to the size, then
+// dropped or blocked.
+//
+//	logger := logging.New(logging.NewAsyncWriter(file))
+//	app.UseHandler(logger) // flushed and closed by app.Close, with the file
+type AsyncWriter struct {
+	w       io.Writer
+	block   bool
+	queue   chan asyncItem
+	dropped uint64
+	mu      sync.RWMutex // guards closed against the writes
+	closed  bool
+	stopped chan struct{}
+	err     atomic.Value
+}
+
+func NewAsyncWriter(w io.Writer, options ...AsyncOptions) *AsyncWriter {
+	opts := AsyncOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Size <= 0 {
+		opts.Size = 1024
+	}
+	a := &AsyncWriter{
+		w:       w,
+		block:   opts.Block,
+		queue:   make(chan asyncItem, opts.Size),
+		stopped: make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+func (a *AsyncWriter) run() {
+	defer close(a.stopped)
+	for item := range a.queue {
+		if item.done != nil {
+			close(item.done)
+			continue
+		}
+		if _, err := a.w.Write(item.buf); err != nil {
+			a.err.Store(err)
+		}
+	}
+}
+
+// Write queues a copy of p. It never fails, except after Close, a failure of
+// the underlying writer is returned by Flush.
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return 0, os.ErrClosed
+	}
+
+	item := asyncItem{buf: append([]byte(nil), p...)}
+	if a.block {
+		a.queue <- item
+		return len(p), nil
+	}
+	select {
+	case a.queue <- item:
+	default:
+		atomic.AddUint64(&a.dropped, 1)
+	}
+	return len(p), nil
+}
+
+// Dropped returns the number of writes dropped because the buffer was full.
+func (a *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&a.dropped)
+}
+
+// Flush waits for the queued writes, it returns the last error of the
+// underlying writer.
+func (a *AsyncWriter) Flush() error {
+	a.mu.RLock()
+	if !a.closed {
+		done := make(chan struct{})
+		a.queue <- asyncItem{done: done}
+		a.mu.RUnlock()
+		<-done
+	} else {
+		a.mu.RUnlock()
+	}
+	if err, ok := a.err.Load().(error); ok {
+		return err
+	}
+	return nil
+}
+
+// Close flushes the queued writes and stops the writer, then closes the
+// underlying writer if it is a RotatingFile, SyslogWriter or AsyncWriter.
+// Closing it again is a no-op.
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	closed := a.closed
+	if !closed {
+		a.closed = true
+		close(a.queue)
+	}
+	a.mu.Unlock()
+	<-a.stopped
+	if closed {
+		return nil
+	}
+	err := closeWriter(a.w)
+	if e, ok := a.err.Load().(error); ok {
+		return e
+	}
+	return err
+}
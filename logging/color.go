@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+type ColorType uint8
+
+const (
+	ColorRed ColorType = iota + 1
+	ColorGreen
+	ColorYellow
+	ColorBlue
+	ColorMagents
+	ColorCyan
+	ColorWhite
+	ColorGray
+)
+
+// ansiColors are the ANSI SGR codes of the colors.
+var ansiColors = map[ColorType]string{
+	ColorRed:     "\x1b[91m",
+	ColorGreen:   "\x1b[92m",
+	ColorYellow:  "\x1b[93m",
+	ColorBlue:    "\x1b[94m",
+	ColorMagents: "\x1b[95m",
+	ColorCyan:    "\x1b[96m",
+	ColorWhite:   "\x1b[97m",
+	ColorGray:    "\x1b[37m",
+}
+
+const ansiReset = "\x1b[0m"
+
+// ColorEnabled reports whether colors are written to w: w must be a terminal,
+// and the NO_COLOR environment variable must be unset or empty
+// (https://no-color.org).
+func ColorEnabled(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	return ok && isTerminal(f)
+}
+
+// FprintWithColor writes str in the color if w is a color terminal, or as is.
+func FprintWithColor(w io.Writer, str string, code ColorType) (int, error) {
+	if !ColorEnabled(w) {
+		return fmt.Fprint(w, str)
+	}
+	return fprintColor(w.(*os.File), str, code)
+}
+
+// ansiEnabled reports whether ANSI color sequences can be written to w, so
+// that a colored line is written at once.
+func ansiEnabled(w io.Writer) bool {
+	return ColorEnabled(w) && supportsANSI(w.(*os.File))
+}
+
+func fprintANSI(w io.Writer, str string, code ColorType) (int, error) {
+	return fmt.Fprint(w, ansiColors[code]+str+ansiReset)
+}
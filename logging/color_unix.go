@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package logging
+
+import "os"
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func fprintColor(f *os.File, str string, code ColorType) (int, error) {
+	return fprintANSI(f, str, code)
+}
+
+func supportsANSI(f *os.File) bool {
+	return true
+}
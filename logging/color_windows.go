@@ -1,40 +1,77 @@
 package logging
 
 import (
-	"io"
 	"fmt"
+	"os"
+	"sync"
 	"syscall"
 )
 
-type ColorType uint16
-
-const (
-	ColorRed     ColorType = 0x0004 | 0x0008
-	ColorGreen   ColorType = 0x0002 | 0x0008
-	ColorYellow  ColorType = 0x0004 | 0x0002 | 0x0008
-	ColorBlue    ColorType = 0x0001 | 0x0008
-	ColorMagents ColorType = 0x0001 | 0x0004 | 0x0008
-	ColorCyan    ColorType = 0x0002 | 0x0001 | 0x0008
-	ColorWhite   ColorType = 0x0004 | 0x0001 | 0x0002 | 0x0008
-	ColorGray    ColorType = 0x0004 | 0x0002 | 0x0001
-)
+const enableVirtualTerminalProcessing = 0x0004
+
+// consoleAttributes are the legacy console attributes of the colors.
+var consoleAttributes = map[ColorType]uint16{
+	ColorRed:     0x0004 | 0x0008,
+	ColorGreen:   0x0002 | 0x0008,
+	ColorYellow:  0x0004 | 0x0002 | 0x0008,
+	ColorBlue:    0x0001 | 0x0008,
+	ColorMagents: 0x0001 | 0x0004 | 0x0008,
+	ColorCyan:    0x0002 | 0x0001 | 0x0008,
+	ColorWhite:   0x0004 | 0x0001 | 0x0002 | 0x0008,
+	ColorGray:    0x0004 | 0x0002 | 0x0001,
+}
 
 var (
-	kernel32 = syscall.NewLazyDLL("kernel32.dll")
+	kernel32                   = syscall.NewLazyDLL("kernel32.dll")
 	proSetConsoleTextAttribute = kernel32.NewProc("SetConsoleTextAttribute")
+	procSetConsoleMode         = kernel32.NewProc("SetConsoleMode")
+
+	// vtMode caches whether the console handles accept ANSI sequences.
+	vtMode sync.Map
 )
 
-func setConsoleTextAttribute(wAttributes uint16) bool {
+func isTerminal(f *os.File) bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(f.Fd()), &mode) == nil
+}
+
+// enableVTMode enables the ANSI sequences on Windows 10 and later consoles.
+func enableVTMode(handle syscall.Handle) bool {
+	if ok, loaded := vtMode.Load(handle); loaded {
+		return ok.(bool)
+	}
+	var mode uint32
+	ok := syscall.GetConsoleMode(handle, &mode) == nil
+	if ok && mode&enableVirtualTerminalProcessing == 0 {
+		ret, _, _ := procSetConsoleMode.Call(uintptr(handle), uintptr(mode|enableVirtualTerminalProcessing))
+		ok = ret != 0
+	}
+	vtMode.Store(handle, ok)
+	return ok
+}
+
+// supportsANSI reports whether the console accepts ANSI sequences, legacy
+// consoles only support colors by console attributes.
+func supportsANSI(f *os.File) bool {
+	return enableVTMode(syscall.Handle(f.Fd()))
+}
+
+func setConsoleTextAttribute(handle syscall.Handle, wAttributes uint16) bool {
 	ret, _, _ := proSetConsoleTextAttribute.Call(
-		uintptr(syscall.Stdout),
+		uintptr(handle),
 		uintptr(wAttributes),
 	)
 	return ret != 0
 }
 
-func FprintWithColor(w io.Writer, str string, code ColorType) (int, error) {
-	if setConsoleTextAttribute(uint16(code)) {
-		defer setConsoleTextAttribute(uint16(ColorGray))
+func fprintColor(f *os.File, str string, code ColorType) (int, error) {
+	handle := syscall.Handle(f.Fd())
+	if enableVTMode(handle) {
+		return fprintANSI(f, str, code)
+	}
+	// legacy consoles
+	if setConsoleTextAttribute(handle, consoleAttributes[code]) {
+		defer setConsoleTextAttribute(handle, consoleAttributes[ColorGray])
 	}
-	return fmt.Fprint(w, str)
-}
\ No newline at end of file
+	return fmt.Fprint(f, str)
+}
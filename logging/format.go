@@ -0,0 +1,60 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Formatter formats a log line, without the trailing newline. It is called
+// with the logger locked.
+type Formatter interface {
+	Format(t time.Time, level Level, msg string, fields Log) ([]byte, error)
+}
+
+// JSONFormatter formats every log as a JSON object with "time", "level",
+// "message" and the fields:
+//
+//	{"Method":"GET","level":"INFO","message":"post created","time":"2017-01-02T15:04:05.999Z"}
+type JSONFormatter struct {
+	// TimeFormat default to time.RFC3339Nano, in UTC.
+	TimeFormat string
+}
+
+func (f JSONFormatter) Format(t time.Time, level Level, msg string, fields Log) ([]byte, error) {
+	tf := f.TimeFormat
+	if tf == "" {
+		tf = time.RFC3339Nano
+	}
+	obj := make(map[string]interface{}, len(fields)+3)
+	for key, val := range fields {
+		if err, ok := val.(error); ok {
+			val = err.Error()
+		}
+		obj[key] = val
+	}
+	obj["time"] = t.UTC().Format(tf)
+	obj["level"] = level.String()
+	obj["message"] = msg
+	return json.Marshal(obj)
+}
+
+// appendTextFields appends the fields as " key=value" ordered by key.
+func appendTextFields(buf []byte, fields Log) []byte {
+	if len(fields) == 0 {
+		return buf
+	}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		buf = append(buf, ' ')
+		buf = append(buf, key...)
+		buf = append(buf, '=')
+		buf = append(buf, crlfEscaper.Replace(fmt.Sprint(fields[key]))...)
+	}
+	return buf
+}
@@ -4,6 +4,7 @@ import (
 	"io"
 	"sync"
 	"goblog"
+	"goblog/requestid"
 	"os"
 	"time"
 	"encoding/json"
@@ -11,6 +12,8 @@ import (
 	"strings"
 	"strconv"
 	"bytes"
+	"sync/atomic"
+	"net/http"
 )
 
 var crlfEscaper = strings.NewReplacer("\r", "\\r", "\n", "\\n")
@@ -64,6 +67,40 @@ const (
 )
 
 var levels = []string{"EMERG", "ALERT", "CRIT", "ERR", "WARNING", "NOTICE", "INFO", "DEBUG"}
+
+func (level Level) String() string {
+	if level > DebugLevel {
+		return "Level(" + strconv.Itoa(int(level)) + ")"
+	}
+	return levels[level]
+}
+
+var levelAliases = map[string]Level{
+	"EMERGENCY":     EmergLevel,
+	"CRITICAL":      CritiLevel,
+	"ERROR":         ErrLevel,
+	"WARN":          WarningLevel,
+	"INFORMATIONAL": InfoLevel,
+}
+
+// ParseLevel parses a level name such as "info" or "WARNING", or a number
+// from 0 (EMERG) to 7 (DEBUG).
+func ParseLevel(s string) (Level, error) {
+	name := strings.ToUpper(strings.TrimSpace(s))
+	for i, str := range levels {
+		if name == str {
+			return Level(i), nil
+		}
+	}
+	if level, ok := levelAliases[name]; ok {
+		return level, nil
+	}
+	if i, err := strconv.Atoi(name); err == nil && i >= 0 && i <= int(DebugLevel) {
+		return Level(i), nil
+	}
+	return 0, fmt.Errorf("invalid logger level %q", s)
+}
+
 var std = New(os.Stderr)
 
 func Default(devMode ...bool) *Logger {
@@ -78,12 +115,31 @@ func developmentConsume(log Log, ctx *goblog.Context) {
 	defer std.mu.Unlock()
 
 	end := time.Now().UTC()
-	FprintWithColor(std.Out, fmt.Sprintf("%s", log["IP"]), ColorGreen)
-	fmt.Fprintf(std.Out, ` - - [%s] "%s %s %s"`, end.Format(std.tf), log["Method"], log["URL"], log["Proto"])
 	status := log["Status"].(int)
-	FprintWithColor(std.Out, strconv.Itoa(status), colorStatus(status))
-	resTime := float64(end.Sub(log["Start"].(time.Time))) / 1e6
-	fmt.Fprintln(std.Out, fmt.Sprintf("%s %.3fms", log["Length"], resTime))
+	line := func(color bool) []byte {
+		buf := new(bytes.Buffer)
+		writeColor(buf, fmt.Sprintf("%s", log["IP"]), ColorGreen, color)
+		fmt.Fprintf(buf, ` - - [%s] "%s %s %s" `, end.Format(std.tf), log["Method"], log["URL"], log["Proto"])
+		writeColor(buf, strconv.Itoa(status), colorStatus(status), color)
+		resTime := float64(end.Sub(log["Start"].(time.Time))) / 1e6
+		fmt.Fprintf(buf, " %v %.3fms\n", log["Length"], resTime)
+		return buf.Bytes()
+	}
+	// the colors are only written to a terminal Out, not to the outputs
+	plain := line(false)
+	if ansiEnabled(std.Out) {
+		std.emitColored(InfoLevel, line(true), plain)
+	} else {
+		std.emit(InfoLevel, plain)
+	}
+}
+
+func writeColor(buf *bytes.Buffer, str string, code ColorType, color bool) {
+	if color {
+		fprintANSI(buf, str, code)
+	} else {
+		buf.WriteString(str)
+	}
 }
 
 func New(w io.Writer) *Logger {
@@ -99,6 +155,9 @@ func New(w io.Writer) *Logger {
 		log["Proto"] = ctx.Req.Proto
 		log["UserAgent"] = ctx.Get(goblog.HeaderUserAgent)
 		log["Start"] = time.Now()
+		if id := requestid.FromCtx(ctx); id != "" {
+			log["RequestID"] = id
+		}
 	}
 
 	logger.consume = func(log Log, _ *goblog.Context) {
@@ -108,7 +167,10 @@ func New(w io.Writer) *Logger {
 			delete(log, "Start")
 		}
 
-		if str, err := log.Format(); err == nil {
+		if logger.structured() {
+			// structured formatters log the fields as is
+			logger.write(end, InfoLevel, fmt.Sprintf("%s %s", log["Method"], log["URL"]), log)
+		} else if str, err := log.Format(); err == nil {
 			logger.Output(end, InfoLevel, str)
 		} else {
 			logger.Output(end, WarningLevel, log.String())
@@ -119,53 +181,360 @@ func New(w io.Writer) *Logger {
 
 type Logger struct {
 	Out 	io.Writer
-	l 		Level
+	l 		uint32 // Level, accessed atomically
 	tf, lf 	string
 	mu 		sync.Mutex
 	init 	func(Log, *goblog.Context)
 	consume func(Log, *goblog.Context)
+	formatter Formatter
+	outputs []output
+	// child loggers share the writer, level and formats of the root logger
+	parent  *Logger
+	fields  Log
+}
+
+func (l *Logger) root() *Logger {
+	if l.parent != nil {
+		return l.parent
+	}
+	return l
+}
+
+// With returns a child logger adding the fields to every log. Child loggers
+// share the writer, level and format of the logger, settings always apply to
+// the root logger.
+func (l *Logger) With(fields Log) *Logger {
+	merged := make(Log, len(l.fields)+len(fields))
+	for key, val := range l.fields {
+		merged[key] = val
+	}
+	for key, val := range fields {
+		merged[key] = val
+	}
+	return &Logger{parent: l.root(), fields: merged}
 }
 
+type requestLogger struct{ l *Logger }
+
+func (r requestLogger) New(ctx *goblog.Context) (interface{}, error) {
+	fields := Log{"Method": ctx.Method, "Path": ctx.Path}
+	if id := requestid.FromCtx(ctx); id != "" {
+		fields["RequestID"] = id
+	}
+	return r.l.With(fields), nil
+}
+
+// Request returns the child logger of the request, created once per request
+// with the method, path and request ID fields.
+//
+//	logger.Request(ctx).Infof("post %s created", id)
+func (l *Logger) Request(ctx *goblog.Context) *Logger {
+	any, _ := ctx.Any(requestLogger{l})
+	return any.(*Logger)
+}
+
+// Output writes the log if the level is enabled.
 func (l *Logger) Output(t time.Time, level Level, s string) (err error) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	return l.write(t, level, s, nil)
+}
+
+func (l *Logger) write(t time.Time, level Level, s string, fields Log) (err error) {
+	r := l.root()
+	if level > r.GetLevel() {
+		return
+	}
+	if len(l.fields) > 0 {
+		merged := make(Log, len(l.fields)+len(fields))
+		for key, val := range l.fields {
+			merged[key] = val
+		}
+		for key, val := range fields {
+			merged[key] = val
+		}
+		fields = merged
+	}
 
-	// log level checked before
 	if level < 4 {
-		s = goblog.ErrorWithStack(s, 4).String()
+		s = goblog.ErrorWithStack(s, 5).String()
 	}
 	if l := len(s); l > 0 && s[l-1] == '\n' {
 		s = s[0 : l-1]
 	}
-	_, err = fmt.Fprintf(l.Out, l.lf, t.UTC().Format(l.tf), levels[level], crlfEscaper.Replace(s))
-	if err == nil {
-		l.Out.Write([]byte{'\n'})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var buf []byte
+	if r.formatter != nil {
+		if buf, err = r.formatter.Format(t, level, s, fields); err != nil {
+			return
+		}
+	} else {
+		buf = []byte(fmt.Sprintf(r.lf, t.UTC().Format(r.tf), levels[level], crlfEscaper.Replace(s)))
+		buf = appendTextFields(buf, fields)
+	}
+	return r.emit(level, append(buf, '\n'))
+}
+
+type output struct {
+	w     io.Writer
+	level Level
+}
+
+// AddOutput adds a writer receiving the logs up to the level, besides Out.
+// The logger level still applies first.
+//
+//	logger.AddOutput(errorFile, logging.ErrLevel)
+func (l *Logger) AddOutput(w io.Writer, level Level) {
+	l = l.root()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.outputs = append(l.outputs, output{w: w, level: level})
+}
+
+// Flush waits for the async writers of the logger, Out and the outputs.
+func (l *Logger) Flush() (err error) {
+	for _, w := range l.root().writers() {
+		if a, ok := w.(*AsyncWriter); ok {
+			if e := a.Flush(); e != nil && err == nil {
+				err = e
+			}
+		}
 	}
 	return
 }
 
-func (l *Logger) SetLevel(level Level) {
+// Close flushes and closes the writers of this package used by the logger,
+// AsyncWriter, RotatingFile and SyslogWriter, and those behind an AsyncWriter.
+// Other writers such as os.Stderr are not closed.
+func (l *Logger) Close() (err error) {
+	for _, w := range l.root().writers() {
+		if e := closeWriter(w); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// CloseOnShutdown implements goblog.ShutdownCloser, a logger used by
+// app.UseHandler is closed by app.Close, so that buffered logs are not lost.
+func (l *Logger) CloseOnShutdown() error {
+	return l.Close()
+}
+
+// closeWriter closes the writers of this package, other writers are owned by
+// the caller.
+func closeWriter(w io.Writer) error {
+	switch w.(type) {
+	case *AsyncWriter, *RotatingFile, *SyslogWriter:
+		return w.(io.Closer).Close()
+	}
+	return nil
+}
+
+func (l *Logger) writers() []io.Writer {
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	ws := []io.Writer{l.Out}
+	for _, o := range l.outputs {
+		ws = append(ws, o.w)
+	}
+	return ws
+}
+
+// emit writes the line to Out and the outputs of the level, it should be
+// called with the logger locked.
+func (l *Logger) emit(level Level, line []byte) error {
+	return l.emitColored(level, line, line)
+}
+
+// emitColored writes the colored line to Out and the plain line to the
+// outputs of the level.
+func (l *Logger) emitColored(level Level, colored, plain []byte) (err error) {
+	_, err = l.Out.Write(colored)
+	for _, o := range l.outputs {
+		if level <= o.level {
+			if _, e := o.w.Write(plain); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	return
+}
+
+// SetLevel sets the max level to log, it is safe to call at runtime.
+func (l *Logger) SetLevel(level Level) {
 	if level > DebugLevel {
 		panic(goblog.Err.WithMsg("invalid logger level"))
 	}
-	l.l = level
+	atomic.StoreUint32(&l.root().l, uint32(level))
+}
+
+func (l *Logger) GetLevel() Level {
+	return Level(atomic.LoadUint32(&l.root().l))
+}
+
+// Enabled reports whether logs of the level are written.
+func (l *Logger) Enabled(level Level) bool {
+	return level <= l.GetLevel()
+}
+
+// Emerg produces a "Emergency" log, system is unusable.
+func (l *Logger) Emerg(v interface{}) {
+	l.output(EmergLevel, v)
+}
+
+// Emergf produces a "Emergency" log in the format.
+func (l *Logger) Emergf(format string, args ...interface{}) {
+	l.outputf(EmergLevel, format, args)
+}
+
+// Alert produces a "Alert" log, action must be taken immediately.
+func (l *Logger) Alert(v interface{}) {
+	l.output(AlertLevel, v)
+}
+
+// Alertf produces a "Alert" log in the format.
+func (l *Logger) Alertf(format string, args ...interface{}) {
+	l.outputf(AlertLevel, format, args)
+}
+
+// Crit produces a "Critical" log, critical conditions.
+func (l *Logger) Crit(v interface{}) {
+	l.output(CritiLevel, v)
+}
+
+// Critf produces a "Critical" log in the format.
+func (l *Logger) Critf(format string, args ...interface{}) {
+	l.outputf(CritiLevel, format, args)
+}
+
+// Err produces a "Error" log, error conditions.
+func (l *Logger) Err(v interface{}) {
+	l.output(ErrLevel, v)
+}
+
+// Errf produces a "Error" log in the format.
+func (l *Logger) Errf(format string, args ...interface{}) {
+	l.outputf(ErrLevel, format, args)
+}
+
+// Warning produces a "Warning" log, warning conditions.
+func (l *Logger) Warning(v interface{}) {
+	l.output(WarningLevel, v)
+}
+
+// Warningf produces a "Warning" log in the format.
+func (l *Logger) Warningf(format string, args ...interface{}) {
+	l.outputf(WarningLevel, format, args)
+}
+
+// Notice produces a "Notice" log, normal but significant conditions.
+func (l *Logger) Notice(v interface{}) {
+	l.output(NoticeLevel, v)
+}
+
+// Noticef produces a "Notice" log in the format.
+func (l *Logger) Noticef(format string, args ...interface{}) {
+	l.outputf(NoticeLevel, format, args)
+}
+
+// Info produces a "Informational" log, informational messages.
+func (l *Logger) Info(v interface{}) {
+	l.output(InfoLevel, v)
+}
+
+// Infof produces a "Informational" log in the format.
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.outputf(InfoLevel, format, args)
+}
+
+// Debug produces a "Debug" log, debug-level messages.
+func (l *Logger) Debug(v interface{}) {
+	l.output(DebugLevel, v)
+}
+
+// Debugf produces a "Debug" log in the format.
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.outputf(DebugLevel, format, args)
+}
+
+func (l *Logger) output(level Level, v interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	var s string
+	switch val := v.(type) {
+	case string:
+		s = val
+	case Log:
+		if str, err := val.Format(); err == nil {
+			s = str
+		} else {
+			s = val.String()
+		}
+	case error:
+		s = val.Error()
+	default:
+		s = fmt.Sprint(v)
+	}
+	l.Output(time.Now(), level, s)
+}
+
+func (l *Logger) outputf(level Level, format string, args []interface{}) {
+	if l.Enabled(level) {
+		l.Output(time.Now(), level, fmt.Sprintf(format, args...))
+	}
+}
+
+// ServeLevel is an admin route handler to read or change the level at
+// runtime, it should be guarded by authentication:
+//
+//	router.Get("/admin/log-level", auth, logger.ServeLevel)
+//	router.Handle("PUT", "/admin/log-level", auth, logger.ServeLevel) // ?level=debug
+func (l *Logger) ServeLevel(ctx *goblog.Context) error {
+	if ctx.Method == http.MethodPut || ctx.Method == http.MethodPost {
+		level, err := ParseLevel(ctx.Req.URL.Query().Get("level"))
+		if err != nil {
+			return goblog.ErrBadRequest.From(err)
+		}
+		l.SetLevel(level)
+	}
+	return ctx.JSON(http.StatusOK, map[string]string{"level": l.GetLevel().String()})
 }
 
 func (l *Logger) SetTimeFormat(timeFormat string) {
+	l = l.root()
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	l.tf = timeFormat
 }
 
 func (l *Logger) SetLogFormat(logFormat string) {
+	l = l.root()
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	l.lf = logFormat
 }
 
+// SetFormatter sets a structured formatter such as JSONFormatter, replacing
+// the text format of SetTimeFormat and SetLogFormat.
+func (l *Logger) SetFormatter(f Formatter) {
+	l = l.root()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.formatter = f
+}
+
+// structured reports whether a Formatter is set.
+func (l *Logger) structured() bool {
+	l = l.root()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.formatter != nil
+}
+
 func (l *Logger) SetLogConsume(fn func(Log, *goblog.Context)) {
+	l = l.root()
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	l.consume = fn
@@ -173,12 +542,12 @@ func (l *Logger) SetLogConsume(fn func(Log, *goblog.Context)) {
 
 func (l *Logger) New(ctx *goblog.Context) (interface{}, error) {
 	log := Log{}
-	l.init(log, ctx)
+	l.root().init(log, ctx)
 	return log, nil
 }
 
 func (l *Logger) FromCtx(ctx *goblog.Context) Log {
-	any, _ := ctx.Any(l)
+	any, _ := ctx.Any(l.root())
 	return any.(Log)
 }
 
@@ -192,7 +561,13 @@ func (l *Logger) Serve(ctx *goblog.Context) error {
 		}
 		log["Status"] = ctx.Res.Status()
 		log["Length"] = len(ctx.Res.Body())
-		l.consume(log, ctx)
+		// the request ID middleware may run after the logger
+		if _, ok := log["RequestID"]; !ok {
+			if id := requestid.FromCtx(ctx); id != "" {
+				log["RequestID"] = id
+			}
+		}
+		l.root().consume(log, ctx)
 	})
 	return nil
 }
@@ -1,7 +1,328 @@
 package logging
 
-import "testing"
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"goblog"
+)
 
 func TestDefault(t *testing.T) {
-	Default(true)
+	logger := Default(true)
+	out, output := new(bytes.Buffer), new(bytes.Buffer)
+	logger.Out = out
+	logger.AddOutput(output, InfoLevel)
+	defer func() {
+		logger.Out = os.Stderr
+		logger.outputs = nil
+	}()
+
+	app := goblog.New()
+	done := make(chan struct{})
+	app.Use(func(ctx *goblog.Context) error {
+		ctx.OnEnd(func() { close(done) })
+		return nil
+	})
+	app.UseHandler(logger)
+	app.Use(func(ctx *goblog.Context) error {
+		return ctx.HTML(200, "hello")
+	})
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/post", nil))
+	<-done
+
+	if line := out.String(); !strings.Contains(line, `] "GET /post HTTP/1.1" 200 5 `) || output.String() != line {
+		t.Fatalf("expected the development line in Out and the outputs, got %q and %q", line, output.String())
+	}
+}
+
+func TestLogger_Level(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := New(buf)
+	logger.SetLevel(InfoLevel)
+	logger.Debug("hidden")
+	logger.Infof("visible %d", 1)
+	logger.Warning("warned")
+	if out := buf.String(); strings.Contains(out, "hidden") || !strings.Contains(out, "INFO visible 1") ||
+		!strings.Contains(out, "WARNING warned") {
+		t.Fatalf("unexpected output %q", out)
+	}
+
+	for s, expected := range map[string]Level{"debug": DebugLevel, "Error": ErrLevel, "warn": WarningLevel, "2": CritiLevel} {
+		if level, err := ParseLevel(s); err != nil || level != expected {
+			t.Errorf("ParseLevel(%q): expected %s, got %s %v", s, expected, level, err)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("expected invalid level error")
+	}
+}
+
+func TestLogger_JSON(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := New(buf)
+	logger.SetFormatter(JSONFormatter{})
+
+	app := goblog.New()
+	app.Use(func(ctx *goblog.Context) error {
+		logger.Request(ctx).With(Log{"PostID": 1}).Info("post created")
+		return ctx.End(204)
+	})
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/post", nil))
+
+	line := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("expected JSON line, got %q", buf.String())
+	}
+	if line["level"] != "INFO" || line["message"] != "post created" || line["Method"] != "POST" ||
+		line["Path"] != "/post" || line["PostID"] != 1.0 || line["time"] == nil {
+		t.Fatalf("unexpected line %v", line)
+	}
+
+	buf.Reset()
+	logger.SetFormatter(nil)
+	logger.With(Log{"b": 2, "a": "x\ny"}).Notice("started")
+	if out := buf.String(); !strings.HasSuffix(out, " NOTICE started a=x\\ny b=2\n") {
+		t.Fatalf("unexpected text line %q", out)
+	}
+}
+
+func TestLogger_AccessFormat(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := New(buf)
+	logger.SetAccessFormat(`:method :url HTTP/:http-version :status :res[content-length] :req[X-Client] :res[Content-Type] :route :remote-user`)
+
+	app := goblog.New()
+	done := make(chan struct{})
+	app.Use(func(ctx *goblog.Context) error {
+		// end hooks run asynchronously in LIFO order, after the logger hook
+		ctx.OnEnd(func() { close(done) })
+		return nil
+	})
+	app.UseHandler(logger)
+	app.Use(func(ctx *goblog.Context) error {
+		return ctx.JSON(200, []int{1, 2})
+	})
+	req := httptest.NewRequest("GET", "/posts?page=2", nil)
+	req.Header.Set("X-Client", "sdk")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+	<-done
+
+	expected := "GET /posts?page=2 HTTP/1.1 200 5 sdk application/json; charset=utf-8 - -\n"
+	if buf.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	logger.SetFormatter(SyslogFormatter{Hostname: "web1", AppName: "blog"})
+	done = make(chan struct{})
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	<-done
+	if line := buf.String(); !strings.HasPrefix(line, "<14>1 ") ||
+		!strings.HasSuffix(line, " web1 blog "+strconv.Itoa(os.Getpid())+" - - GET / HTTP/1.1 200 5 - application/json; charset=utf-8 - -\n") {
+		t.Fatalf("expected syslog access line, got %q", line)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected unknown token panic")
+		}
+	}()
+	logger.SetAccessFormat(":method :unknown")
+}
+
+func TestRotatingFile(t *testing.T) {
+	if _, err := NewRotatingFile(RotateOptions{}); err == nil {
+		t.Fatal("expected Filename required error")
+	}
+
+	dir := t.TempDir()
+	file, err := NewRotatingFile(RotateOptions{Filename: filepath.Join(dir, "app.log"), MaxSize: 20, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	errOutput := new(bytes.Buffer)
+	logger := New(file)
+	logger.SetLogFormat("%.0s%.0s%s")
+	logger.AddOutput(errOutput, ErrLevel)
+
+	expected := new(strings.Builder)
+	for i := 0; i < 50; i++ {
+		logger.Infof("line %02d", i) // several rotations within a millisecond
+		fmt.Fprintf(expected, "line %02d\n", i)
+	}
+	logger.Warning("warned")
+	logger.Err("failed")
+	if err = file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if out := errOutput.String(); !strings.Contains(out, "failed") || strings.Contains(out, "warned") {
+		t.Fatalf("expected only errors in the error output, got %q", out)
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "app-*.log*"))
+	backups := make([]backupFile, 0, len(matches))
+	for _, name := range matches {
+		b, ok := parseBackup(name, filepath.Join(dir, "app-"), ".log")
+		if !ok || !strings.HasSuffix(name, ".gz") {
+			t.Fatalf("unexpected backup %s", name)
+		}
+		backups = append(backups, b)
+	}
+	sortBackups(backups)
+	all := new(bytes.Buffer)
+	for _, b := range backups {
+		f, _ := os.Open(b.name)
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(all, zr)
+		f.Close()
+	}
+	current, _ := os.ReadFile(filepath.Join(dir, "app.log"))
+	all.Write(current)
+	if !strings.HasPrefix(all.String(), expected.String()+"warned\n") {
+		t.Fatalf("expected no lost lines in %d backups, got %q", len(backups), all.String())
+	}
+
+	dir = t.TempDir()
+	file, _ = NewRotatingFile(RotateOptions{Filename: filepath.Join(dir, "app.log"), MaxSize: 20, MaxBackups: 2})
+	logger = New(file)
+	logger.SetLogFormat("%.0s%.0s%s")
+	for i := 1; i <= 7; i++ {
+		logger.Infof("line %d", i)
+	}
+	file.Close()
+	if buf, _ := os.ReadFile(filepath.Join(dir, "app.log")); string(buf) != "line 7\n" {
+		t.Fatalf("unexpected current file %q", buf)
+	}
+	if backups, _ := filepath.Glob(filepath.Join(dir, "app-*.log")); len(backups) != 2 {
+		t.Fatalf("expected 2 backups, got %v", backups)
+	}
+}
+
+type slowWriter struct {
+	bytes.Buffer
+	delay time.Duration
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(w.delay)
+	return w.Buffer.Write(p)
+}
+
+func TestAsyncWriter(t *testing.T) {
+	w := &slowWriter{delay: 5 * time.Millisecond}
+	async := NewAsyncWriter(w, AsyncOptions{Size: 2})
+	logger := New(async)
+	logger.SetLogFormat("%.0s%.0s%s")
+	for i := 0; i < 10; i++ {
+		logger.Infof("line %d", i)
+	}
+	if err := logger.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(w.String(), "\n"); async.Dropped() == 0 || uint64(lines)+async.Dropped() != 10 {
+		t.Fatalf("expected dropped lines, got %d written and %d dropped", lines, async.Dropped())
+	}
+
+	w = &slowWriter{delay: time.Millisecond}
+	async = NewAsyncWriter(w, AsyncOptions{Size: 16, Block: true}) // queued until app.Close
+	logger = New(async)
+	logger.SetLogFormat("%.0s%.0s%s")
+	app := goblog.New()
+	app.UseHandler(logger)
+	for i := 0; i < 10; i++ {
+		logger.Infof("line %d", i)
+	}
+	app.Close()
+	if lines := strings.Count(w.String(), "\n"); lines != 10 || async.Dropped() != 0 {
+		t.Fatalf("expected all lines written on close, got %d", lines)
+	}
+	if _, err := async.Write([]byte("late\n")); err == nil {
+		t.Fatal("expected write error after close")
+	}
+
+	file, err := NewRotatingFile(RotateOptions{Filename: filepath.Join(t.TempDir(), "app.log")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger = New(NewAsyncWriter(file))
+	logger.Info("hello")
+	if err = logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = file.Write([]byte("late\n")); err != os.ErrClosed {
+		t.Fatalf("expected the file behind the async writer closed, got %v", err)
+	}
+	if err = logger.Close(); err != nil || file.Rotate() != os.ErrClosed {
+		t.Fatalf("expected closing again to be a no-op, got %v", err)
+	}
+}
+
+func TestFprintWithColor(t *testing.T) {
+	buf := new(bytes.Buffer)
+	FprintWithColor(buf, "200", ColorGreen)
+	if buf.String() != "200" || ColorEnabled(buf) {
+		t.Fatalf("expected no color for non terminal writers, got %q", buf.String())
+	}
+
+	buf.Reset()
+	fprintANSI(buf, "500", ColorRed)
+	if buf.String() != "\x1b[91m500\x1b[0m" {
+		t.Fatalf("unexpected ANSI output %q", buf.String())
+	}
+}
+
+func TestSyslog(t *testing.T) {
+	ts := time.Date(2017, 1, 2, 15, 4, 5, 0, time.UTC)
+	f := SyslogFormatter{Facility: FacilityLocal0, Hostname: "web1", AppName: "blog"}
+	buf, _ := f.Format(ts, ErrLevel, "db down", Log{"Path": "/post", "q": `a"]`})
+	expected := fmt.Sprintf(`<131>1 2017-01-02T15:04:05.000000Z web1 blog %d - [fields@32473 Path="/post" q="a\"\]"] db down`, os.Getpid())
+	if string(buf) != expected {
+		t.Fatalf("expected %q, got %q", expected, buf)
+	}
+
+	f.RFC3164 = true
+	buf, _ = f.Format(ts, InfoLevel, "started", nil)
+	if expected = fmt.Sprintf("<134>Jan  2 15:04:05 web1 blog[%d]: started", os.Getpid()); string(buf) != expected {
+		t.Fatalf("expected %q, got %q", expected, buf)
+	}
+
+	buf, _ = JournaldFormatter{Identifier: "blog"}.Format(ts, WarningLevel, "slow", Log{"request-id": "abc", "_x": "y"})
+	if expected = "PRIORITY=4\nSYSLOG_IDENTIFIER=blog\nMESSAGE=slow\nX=y\nREQUEST_ID=abc"; string(buf) != expected {
+		t.Fatalf("expected %q, got %q", expected, buf)
+	}
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	w, err := NewSyslogWriter("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	logger := New(w)
+	logger.SetFormatter(SyslogFormatter{RFC3164: true, Hostname: "web1", AppName: "blog"})
+	logger.Notice("hello")
+
+	packet := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(packet)
+	if err != nil || !strings.HasPrefix(string(packet[:n]), "<13>") || !strings.HasSuffix(string(packet[:n]), ": hello") {
+		t.Fatalf("unexpected message %q %v", packet[:n], err)
+	}
 }
@@ -0,0 +1,288 @@
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// backupTimeFormat is the timestamp of rotated files, "app-2017-01-02T15-04-05.000.log",
+// followed by a "_1", "_2"... counter when rotated several times within a
+// millisecond.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+type RotateOptions struct {
+	// Filename of the log file, required.
+	Filename string
+	// MaxSize rotates the file before it exceeds the bytes, 0 to disable.
+	MaxSize int64
+	// Daily rotates the file when the day changes.
+	Daily bool
+	// MaxBackups removes the oldest rotated files beyond the number, 0 keeps all.
+	MaxBackups int
+	// Compress gzips the rotated files.
+	Compress bool
+}
+
+// RotatingFile is an io.WriteCloser appending to a file, rotated by size or
+// day. It can also be reopened for external tools such as logrotate.
+//
+//	file, err := logging.NewRotatingFile(logging.RotateOptions{
+//		Filename: "/var/log/blog/app.log", MaxSize: 100 << 20, MaxBackups: 7, Compress: true,
+//	})
+//	logger := logging.New(file)
+type RotatingFile struct {
+	opts   RotateOptions
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	day    string
+	closed bool
+	millWg sync.WaitGroup
+	millMu sync.Mutex
+}
+
+func NewRotatingFile(opts RotateOptions) (*RotatingFile, error) {
+	if opts.Filename == "" {
+		return nil, fmt.Errorf("logging: RotateOptions.Filename required")
+	}
+	f := &RotatingFile{opts: opts}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.opts.Filename), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.opts.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.day = time.Now().Format("2006-01-02")
+	return nil
+}
+
+func (f *RotatingFile) Write(p []byte) (n int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if (f.opts.Daily && time.Now().Format("2006-01-02") != f.day) ||
+		(f.opts.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.opts.MaxSize) {
+		if err = f.rotate(); err != nil {
+			return
+		}
+	}
+	n, err = f.file.Write(p)
+	f.size += int64(n)
+	return
+}
+
+// Rotate rotates the file now.
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	return f.rotate()
+}
+
+func (f *RotatingFile) rotate() error {
+	if f.file != nil {
+		if err := f.file.Close(); err != nil {
+			return err
+		}
+		f.file = nil
+	}
+	backup := f.backupName(time.Now())
+	if err := os.Rename(f.opts.Filename, backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	f.millWg.Add(1)
+	go func() {
+		defer f.millWg.Done()
+		f.mill(backup)
+	}()
+	return nil
+}
+
+// backupName returns the name of a backup rotated at t, which doesn't exist
+// yet, compressed or not.
+func (f *RotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(f.opts.Filename)
+	base := strings.TrimSuffix(f.opts.Filename, ext) + "-" + t.Format(backupTimeFormat)
+	name := base + ext
+	for n := 1; fileExists(name) || fileExists(name+".gz"); n++ {
+		name = base + "_" + strconv.Itoa(n) + ext
+	}
+	return name
+}
+
+func fileExists(name string) bool {
+	_, err := os.Lstat(name)
+	return err == nil
+}
+
+type backupFile struct {
+	name string
+	t    time.Time
+	n    int
+}
+
+// parseBackup parses the timestamp and counter of a backup name.
+func parseBackup(name, prefix, ext string) (backupFile, bool) {
+	b := backupFile{name: name}
+	ts := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz"), ext)
+	if i := strings.LastIndexByte(ts, '_'); i >= 0 {
+		n, err := strconv.Atoi(ts[i+1:])
+		if err != nil || n <= 0 {
+			return b, false
+		}
+		b.n, ts = n, ts[:i]
+	}
+	t, err := time.Parse(backupTimeFormat, ts)
+	b.t = t
+	return b, err == nil
+}
+
+// mill compresses the backup and removes the old backups.
+func (f *RotatingFile) mill(backup string) {
+	f.millMu.Lock()
+	defer f.millMu.Unlock()
+
+	if f.opts.Compress {
+		if err := gzipFile(backup); err != nil {
+			std.Errf("logging: compress %s: %v", backup, err)
+		}
+	}
+	if f.opts.MaxBackups <= 0 {
+		return
+	}
+	ext := filepath.Ext(f.opts.Filename)
+	prefix := strings.TrimSuffix(f.opts.Filename, ext) + "-"
+	matches, _ := filepath.Glob(prefix + "*" + ext + "*")
+	backups := make([]backupFile, 0, len(matches))
+	for _, name := range matches {
+		if b, ok := parseBackup(name, prefix, ext); ok {
+			backups = append(backups, b)
+		}
+	}
+	sortBackups(backups)
+	for len(backups) > f.opts.MaxBackups {
+		os.Remove(backups[0].name)
+		backups = backups[1:]
+	}
+}
+
+// sortBackups sorts the backups from the oldest.
+func sortBackups(backups []backupFile) {
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].t.Equal(backups[j].t) {
+			return backups[i].t.Before(backups[j].t)
+		}
+		return backups[i].n < backups[j].n
+	})
+}
+
+func gzipFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err == nil {
+		err = zw.Close()
+	}
+	if e := dst.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(name + ".gz")
+		return err
+	}
+	return os.Remove(name)
+}
+
+// Reopen closes and reopens the file, after an external tool moved it.
+func (f *RotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+	return f.open()
+}
+
+// ReopenOnSIGHUP reopens the file when the process receives SIGHUP, as
+// logrotate's postrotate scripts send. It returns a function to stop.
+func (f *RotatingFile) ReopenOnSIGHUP() (stop func()) {
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-ch:
+				if err := f.Reopen(); err != nil {
+					std.Errf("logging: reopen %s: %v", f.opts.Filename, err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
+
+// Close closes the file and waits for the pending compressions, closing it
+// again is a no-op.
+func (f *RotatingFile) Close() (err error) {
+	f.mu.Lock()
+	f.closed = true
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+	// no rotation starts after the file is closed
+	f.millWg.Wait()
+	return
+}
@@ -0,0 +1,296 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Facility is the syslog facility, the Level is the severity.
+type Facility uint8
+
+const (
+	FacilityUser   Facility = 1
+	FacilityDaemon Facility = 3
+	FacilityAuth   Facility = 4
+	FacilityLocal0 Facility = 16
+	FacilityLocal1 Facility = 17
+	FacilityLocal2 Facility = 18
+	FacilityLocal3 Facility = 19
+	FacilityLocal4 Facility = 20
+	FacilityLocal5 Facility = 21
+	FacilityLocal6 Facility = 22
+	FacilityLocal7 Facility = 23
+)
+
+var (
+	hostname, _ = os.Hostname()
+	appName     = filepath.Base(os.Args[0])
+)
+
+// SyslogFormatter formats the logs as RFC 5424 syslog messages, or as
+// RFC 3164 (BSD) messages, the fields as structured data or key=value pairs:
+//
+//	logger := logging.New(syslogWriter)
+//	logger.SetFormatter(logging.SyslogFormatter{Facility: logging.FacilityLocal0})
+type SyslogFormatter struct {
+	// RFC3164 uses the legacy BSD format, expected by some local daemons.
+	RFC3164 bool
+	// Facility default to FacilityUser, the kernel facility 0 is reserved.
+	Facility Facility
+	// Hostname and AppName default to os.Hostname and the program name.
+	Hostname string
+	AppName  string
+	// StructuredDataID is the RFC 5424 SD-ID of the fields, default to
+	// "fields@32473".
+	StructuredDataID string
+}
+
+func (f SyslogFormatter) Format(t time.Time, level Level, msg string, fields Log) ([]byte, error) {
+	facility := f.Facility
+	if facility == 0 {
+		facility = FacilityUser
+	}
+	host := f.Hostname
+	if host == "" {
+		host = hostname
+	}
+	app := f.AppName
+	if app == "" {
+		app = appName
+	}
+	pri := int(facility)*8 + int(level)
+
+	buf := new(bytes.Buffer)
+	if f.RFC3164 {
+		fmt.Fprintf(buf, "<%d>%s %s %s[%d]: %s", pri, t.Format(time.Stamp), host, app, os.Getpid(), crlfEscaper.Replace(msg))
+		return appendTextFields(buf.Bytes(), fields), nil
+	}
+
+	fmt.Fprintf(buf, "<%d>1 %s %s %s %d - ", pri, t.Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogHeader(host), syslogHeader(app), os.Getpid())
+	if len(fields) == 0 {
+		buf.WriteByte('-')
+	} else {
+		id := f.StructuredDataID
+		if id == "" {
+			id = "fields@32473"
+		}
+		buf.WriteString("[" + id)
+		for _, key := range sortedKeys(fields) {
+			buf.WriteString(" " + sdName(key) + `="` + sdEscaper.Replace(fmt.Sprint(fields[key])) + `"`)
+		}
+		buf.WriteByte(']')
+	}
+	if msg != "" {
+		buf.WriteString(" " + crlfEscaper.Replace(msg))
+	}
+	return buf.Bytes(), nil
+}
+
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// syslogHeader returns the printable ASCII header field, or "-".
+func syslogHeader(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return -1
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// sdName returns a valid SD-PARAM name, without '=', ' ', ']' and '"'.
+func sdName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(s) > 32 {
+		s = s[:32]
+	}
+	return s
+}
+
+func sortedKeys(fields Log) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// JournaldFormatter formats the logs as journald native entries, with the
+// PRIORITY, SYSLOG_IDENTIFIER, MESSAGE and the fields in upper case.
+//
+//	w, err := logging.NewSyslogWriter("unixgram", logging.JournaldSocket)
+//	logger := logging.New(w)
+//	logger.SetFormatter(logging.JournaldFormatter{})
+type JournaldFormatter struct {
+	// Identifier default to the program name.
+	Identifier string
+}
+
+func (f JournaldFormatter) Format(t time.Time, level Level, msg string, fields Log) ([]byte, error) {
+	id := f.Identifier
+	if id == "" {
+		id = appName
+	}
+	buf := new(bytes.Buffer)
+	writeJournalField(buf, "PRIORITY", strconv.Itoa(int(level)))
+	writeJournalField(buf, "SYSLOG_IDENTIFIER", id)
+	writeJournalField(buf, "MESSAGE", msg)
+	for _, key := range sortedKeys(fields) {
+		if name := journalName(key); name != "" {
+			writeJournalField(buf, name, fmt.Sprint(fields[key]))
+		}
+	}
+	// the logger appends the final newline
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
+
+// writeJournalField writes KEY=value, or the binary form for multi-line values.
+func writeJournalField(buf *bytes.Buffer, key, val string) {
+	if !strings.Contains(val, "\n") {
+		buf.WriteString(key + "=" + val + "\n")
+		return
+	}
+	buf.WriteString(key + "\n")
+	binary.Write(buf, binary.LittleEndian, uint64(len(val)))
+	buf.WriteString(val + "\n")
+}
+
+// journalName returns a valid journal field name: upper case letters, digits
+// and underscores, not starting with an underscore.
+func journalName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, s)
+	s = strings.TrimLeft(s, "_")
+	if len(s) > 64 {
+		s = s[:64]
+	}
+	return s
+}
+
+// JournaldSocket is the journald native protocol socket.
+const JournaldSocket = "/run/systemd/journal/socket"
+
+var localSyslogPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogWriter sends every write as a message to a syslog daemon or collector,
+// reconnecting once on failure. TCP connections use the RFC 6587 octet
+// counting framing, local stream sockets the newline framing.
+type SyslogWriter struct {
+	network, addr string
+	mu            sync.Mutex
+	conn          net.Conn
+	framing       func(msg []byte) []byte
+}
+
+// NewSyslogWriter connects to the syslog server, such as ("udp",
+// "collector:514") or ("tcp", "collector:6514"), or to the local syslog
+// socket if network is "".
+func NewSyslogWriter(network, addr string) (*SyslogWriter, error) {
+	w := &SyslogWriter{network: network, addr: addr}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *SyslogWriter) connect() (err error) {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+	if w.network != "" {
+		if w.conn, err = net.Dial(w.network, w.addr); err == nil {
+			w.setFraming(w.network)
+		}
+		return
+	}
+	for _, path := range localSyslogPaths {
+		for _, network := range []string{"unixgram", "unix"} {
+			if conn, e := net.Dial(network, path); e == nil {
+				w.conn = conn
+				w.setFraming(network)
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("logging: local syslog socket not found")
+}
+
+func (w *SyslogWriter) setFraming(network string) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		w.framing = func(msg []byte) []byte {
+			return append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+		}
+	case "unix":
+		w.framing = func(msg []byte) []byte {
+			return append(msg, '\n')
+		}
+	default: // datagrams, journald entries end with a newline
+		journald := w.addr == JournaldSocket
+		w.framing = func(msg []byte) []byte {
+			if journald {
+				return append(msg, '\n')
+			}
+			return msg
+		}
+	}
+}
+
+// Write sends p as a message, without the trailing newline.
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	msg := bytes.TrimSuffix(p, []byte{'\n'})
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn != nil {
+		if _, err := w.conn.Write(w.framing(msg)); err == nil {
+			return len(p), nil
+		}
+	}
+	if err := w.connect(); err != nil {
+		return 0, err
+	}
+	if _, err := w.conn.Write(w.framing(msg)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *SyslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
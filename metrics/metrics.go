@@ -0,0 +1,124 @@
+// Package metrics records request metrics and exposes them in the Prometheus
+// text exposition format, without a client library.
+//
+//	m := metrics.New(metrics.Options{Path: "/metrics"})
+//	app.UseHandler(m)
+//
+// Requests are labelled by method, route pattern (not the raw path) and status.
+// Recovered panics are counted with the SetOnPanic hook:
+//
+//	app.Set(goblog.SetOnPanic, m.OnPanic)
+package metrics
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+
+	"goblog"
+)
+
+// ContentType of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type Options struct {
+	// Path exposes the metrics, default to "/metrics".
+	Path string
+	// Namespace prefixes the metric names, default to "goblog".
+	Namespace string
+	// Buckets of the request duration histogram, default to DefaultBuckets.
+	Buckets []float64
+	// Registry holds the metrics, default to a new Registry. Custom metrics
+	// can be registered on it.
+	Registry *Registry
+}
+
+type Metrics struct {
+	path     string
+	registry *Registry
+	requests *CounterVec
+	duration *HistogramVec
+	size     *HistogramVec
+	inFlight *GaugeVec
+	panics   *CounterVec
+}
+
+func New(options ...Options) *Metrics {
+	opts := Options{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Path == "" {
+		opts.Path = "/metrics"
+	}
+	if opts.Namespace == "" {
+		opts.Namespace = "goblog"
+	}
+	if opts.Buckets == nil {
+		opts.Buckets = DefaultBuckets
+	}
+	if opts.Registry == nil {
+		opts.Registry = NewRegistry()
+	}
+
+	r := opts.Registry
+	ns := opts.Namespace + "_http_"
+	return &Metrics{
+		path:     opts.Path,
+		registry: r,
+		requests: r.NewCounterVec(ns+"requests_total", "Total number of HTTP requests.", "method", "route", "status"),
+		duration: r.NewHistogramVec(ns+"request_duration_seconds", "HTTP request duration in seconds.",
+			opts.Buckets, "method", "route", "status"),
+		size: r.NewHistogramVec(ns+"response_size_bytes", "HTTP response body size in bytes.",
+			SizeBuckets, "method", "route", "status"),
+		inFlight: r.NewGaugeVec(ns+"requests_in_flight", "Number of HTTP requests being served."),
+		panics:   r.NewCounterVec(ns+"panics_total", "Total number of recovered panics.", "method", "route"),
+	}
+}
+
+// Registry returns the registry of the metrics.
+func (m *Metrics) Registry() *Registry {
+	return m.registry
+}
+
+// Serve implements goblog.Handler interface. It exposes the metrics on the
+// configured path, and records the other requests.
+func (m *Metrics) Serve(ctx *goblog.Context) error {
+	if ctx.Path == m.path && ctx.Method == "GET" {
+		return m.Expose(ctx)
+	}
+
+	start := time.Now()
+	m.inFlight.Add(1)
+	ctx.OnComplete(func() {
+		m.inFlight.Add(-1)
+		route := ctx.RoutePattern()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(ctx.Res.Status())
+		m.requests.Inc(ctx.Method, route, status)
+		m.duration.Observe(time.Since(start).Seconds(), ctx.Method, route, status)
+		m.size.Observe(float64(ctx.Res.Written()), ctx.Method, route, status)
+	})
+	return nil
+}
+
+// OnPanic counts a recovered panic, it is a goblog.SetOnPanic hook.
+func (m *Metrics) OnPanic(ctx *goblog.Context, err *goblog.Error) {
+	route := ctx.RoutePattern()
+	if route == "" {
+		route = "unmatched"
+	}
+	m.panics.Inc(ctx.Method, route)
+}
+
+// Expose responds the metrics, it can also be mounted on a router.
+func (m *Metrics) Expose(ctx *goblog.Context) error {
+	buf := new(bytes.Buffer)
+	if _, err := m.registry.WriteTo(buf); err != nil {
+		return err
+	}
+	ctx.Type(ContentType)
+	return ctx.End(200, buf.Bytes())
+}
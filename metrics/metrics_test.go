@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"goblog"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New()
+	app := goblog.New()
+	done := make(chan struct{}, 1)
+	app.Use(func(ctx *goblog.Context) error {
+		// complete hooks run asynchronously in LIFO order, after the metrics hook
+		ctx.OnComplete(func() { done <- struct{}{} })
+		return nil
+	})
+	app.UseHandler(m)
+	app.Use(func(ctx *goblog.Context) error {
+		if ctx.Path == "/stream" {
+			return ctx.Stream(200, "text/plain", strings.NewReader(strings.Repeat("a", 300)))
+		}
+		return ctx.HTML(200, "hello")
+	})
+
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/post/1", nil))
+	<-done
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/stream", nil))
+	<-done
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/metrics", nil))
+	body := res.Body.String()
+	for _, line := range []string{
+		"# TYPE goblog_http_requests_total counter",
+		`goblog_http_requests_total{method="GET",route="unmatched",status="200"} 2`,
+		`goblog_http_response_size_bytes_bucket{method="GET",route="unmatched",status="200",le="100"} 1`,
+		`goblog_http_response_size_bytes_sum{method="GET",route="unmatched",status="200"} 305`,
+		`goblog_http_request_duration_seconds_count{method="GET",route="unmatched",status="200"} 2`,
+		"goblog_http_requests_in_flight 0",
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected %q in:\n%s", line, body)
+		}
+	}
+	if res.Header().Get(goblog.HeaderContentType) != ContentType {
+		t.Errorf("unexpected content type %q", res.Header().Get(goblog.HeaderContentType))
+	}
+}
@@ -0,0 +1,273 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"goblog"
+)
+
+// DefaultBuckets are the histogram buckets of request durations, in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// SizeBuckets are the histogram buckets of response sizes, in bytes.
+var SizeBuckets = []float64{100, 1000, 10000, 100000, 1e6, 1e7}
+
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry holds metrics and writes them in the Prometheus text exposition format.
+type Registry struct {
+	mu         sync.Mutex
+	names      map[string]bool
+	collectors []collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+func (r *Registry) register(name string, c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[name] {
+		panic(goblog.Err.WithMsgf("metrics: duplicate metric %s", name))
+	}
+	r.names[name] = true
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteTo writes all metrics in the text exposition format version 0.0.4.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+type desc struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+func (d *desc) writeHeader(w *bufio.Writer) {
+	w.WriteString("# HELP " + d.name + " " + helpEscaper.Replace(d.help) + "\n")
+	w.WriteString("# TYPE " + d.name + " " + d.typ + "\n")
+}
+
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(goblog.Err.WithMsgf("metrics: %s expects labels %s", d.name, strings.Join(d.labels, ", ")))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// labelPairs formats `{a="1",b="2"}`, with an optional extra pair such as le.
+func (d *desc) labelPairs(values []string, extraName, extraValue string) string {
+	if len(d.labels) == 0 && extraName == "" {
+		return ""
+	}
+	buf := new(strings.Builder)
+	buf.WriteByte('{')
+	for i, label := range d.labels {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(label + `="` + labelEscaper.Replace(values[i]) + `"`)
+	}
+	if extraName != "" {
+		if len(d.labels) > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(extraName + `="` + extraValue + `"`)
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+type series struct {
+	values []string
+	value  float64
+}
+
+// vec is a family of float64 values by label values.
+type vec struct {
+	desc
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+func (v *vec) add(values []string, delta float64, set bool) {
+	key := v.key(values)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{values: append([]string(nil), values...)}
+		v.series[key] = s
+	}
+	if set {
+		s.value = delta
+	} else {
+		s.value += delta
+	}
+}
+
+func (v *vec) write(w *bufio.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.writeHeader(w)
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := v.series[key]
+		w.WriteString(v.name + v.labelPairs(s.values, "", "") + " " + formatFloat(s.value) + "\n")
+	}
+}
+
+// CounterVec is a counter family partitioned by labels.
+type CounterVec struct{ vec }
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{vec{desc: desc{name, help, "counter", labels}, series: make(map[string]*series)}}
+	r.register(name, c)
+	return c
+}
+
+func (c *CounterVec) Inc(values ...string) {
+	c.add(values, 1, false)
+}
+
+func (c *CounterVec) Add(delta float64, values ...string) {
+	if delta < 0 {
+		panic(goblog.Err.WithMsg("metrics: counter can't decrease"))
+	}
+	c.add(values, delta, false)
+}
+
+// GaugeVec is a gauge family partitioned by labels.
+type GaugeVec struct{ vec }
+
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{vec{desc: desc{name, help, "gauge", labels}, series: make(map[string]*series)}}
+	r.register(name, g)
+	return g
+}
+
+func (g *GaugeVec) Set(value float64, values ...string) {
+	g.add(values, value, true)
+}
+
+func (g *GaugeVec) Add(delta float64, values ...string) {
+	g.add(values, delta, false)
+}
+
+type histogramSeries struct {
+	values []string
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// HistogramVec is a histogram family partitioned by labels.
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &HistogramVec{
+		desc:    desc{name, help, "histogram", labels},
+		buckets: buckets,
+		series:  make(map[string]*histogramSeries),
+	}
+	r.register(name, h)
+	return h
+}
+
+func (h *HistogramVec) Observe(value float64, values ...string) {
+	key := h.key(values)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{values: append([]string(nil), values...), counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	if i := sort.SearchFloat64s(h.buckets, value); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += value
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.writeHeader(w)
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := h.series[key]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			w.WriteString(h.name + "_bucket" + h.labelPairs(s.values, "le", formatFloat(bound)) +
+				" " + strconv.FormatUint(cumulative, 10) + "\n")
+		}
+		w.WriteString(h.name + "_bucket" + h.labelPairs(s.values, "le", "+Inf") + " " + strconv.FormatUint(s.count, 10) + "\n")
+		w.WriteString(h.name + "_sum" + h.labelPairs(s.values, "", "") + " " + formatFloat(s.sum) + "\n")
+		w.WriteString(h.name + "_count" + h.labelPairs(s.values, "", "") + " " + strconv.FormatUint(s.count, 10) + "\n")
+	}
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,118 @@
+package goblog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+)
+
+// Problem is a RFC 7807 problem details object, responded for errors when the
+// SetProblemDetails setting is true.
+type Problem struct {
+	// Type is a URI reference identifying the problem type, default to
+	// "about:blank". It can be set with a "type" string in the Error.Data map.
+	Type     string
+	Title    string
+	Status   int
+	Detail   string
+	Instance string
+	// Extensions are additional members, "code" from Error.AppCode, members
+	// of the Error.Data map, or "data" if Error.Data is not a map.
+	Extensions map[string]interface{}
+}
+
+var problemMembers = map[string]bool{"type": true, "title": true, "status": true, "detail": true, "instance": true}
+
+// NewProblem converts the error to a Problem, the request path is the instance.
+func NewProblem(err HTTPError, instance string) *Problem {
+	p := &Problem{Type: "about:blank", Status: err.Status(), Instance: instance}
+	e, ok := err.(*Error)
+	if !ok {
+		p.Title = http.StatusText(p.Status)
+		p.Detail = err.Error()
+		return p
+	}
+
+	p.Title = e.Err
+	p.Detail = e.Msg
+	if e.AppCode != "" {
+		p.setExtension("code", e.AppCode)
+	}
+	switch data := e.Data.(type) {
+	case nil:
+	case map[string]interface{}:
+		for key, val := range data {
+			if key == "type" {
+				if typ, ok := val.(string); ok && typ != "" {
+					p.Type = typ
+				}
+			} else if !problemMembers[key] {
+				p.setExtension(key, val)
+			}
+		}
+	case map[string]string:
+		for key, val := range data {
+			if key == "type" {
+				if val != "" {
+					p.Type = val
+				}
+			} else if !problemMembers[key] {
+				p.setExtension(key, val)
+			}
+		}
+	default:
+		p.setExtension("data", data)
+	}
+	return p
+}
+
+func (p *Problem) setExtension(key string, val interface{}) {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]interface{})
+	}
+	p.Extensions[key] = val
+}
+
+// MarshalJSON implements json.Marshaler interface, extension members are
+// flattened beside the standard members.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	obj := make(map[string]interface{}, len(p.Extensions)+5)
+	for key, val := range p.Extensions {
+		obj[key] = val
+	}
+	obj["type"] = p.Type
+	obj["title"] = p.Title
+	obj["status"] = p.Status
+	if p.Detail != "" {
+		obj["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		obj["instance"] = p.Instance
+	}
+	return json.Marshal(obj)
+}
+
+// respondProblem responds the error as problem+json, or renders it with the
+// "error" template to clients preferring HTML.
+func (ctx *Context) respondProblem(err HTTPError) {
+	p := NewProblem(err, ctx.Path)
+	if stack := ctx.errorStack(err); stack != "" {
+		p.setExtension("stack", stack)
+	}
+
+	if ctx.app.renderer != nil &&
+		ctx.AcceptType(MIMEApplicationJSON, MIMEApplicationProblemJSON, MIMETextHTML) == MIMETextHTML {
+		buf := new(bytes.Buffer)
+		e := ctx.app.renderer.Render(ctx, buf, "error", p)
+		if e == nil {
+			ctx.Set(HeaderContentType, MIMETextHTMLCharsetUTF8)
+			ctx.Res.respond(p.Status, buf.Bytes())
+			return
+		}
+		ctx.app.Error(e)
+	}
+
+	buf, _ := json.Marshal(p)
+	ctx.Set(HeaderContentType, MIMEApplicationProblemJSON)
+	ctx.Res.respond(p.Status, buf)
+}
@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+// Balancer selects the upstream of a request.
+type Balancer int
+
+const (
+	// RoundRobin selects the healthy upstreams in turn.
+	RoundRobin Balancer = iota
+	// LeastConn selects the healthy upstream with the fewest active requests.
+	LeastConn
+)
+
+type upstream struct {
+	url       *url.URL
+	conns     int64
+	fails     int32
+	downUntil int64 // UnixNano
+}
+
+func (u *upstream) healthy(now time.Time) bool {
+	return atomic.LoadInt64(&u.downUntil) <= now.UnixNano()
+}
+
+// fail records a failure, the upstream is marked down for failTimeout once it
+// failed maxFails times in a row.
+func (u *upstream) fail(maxFails int, failTimeout time.Duration) {
+	if n := atomic.AddInt32(&u.fails, 1); int(n) >= maxFails {
+		atomic.StoreInt32(&u.fails, 0)
+		atomic.StoreInt64(&u.downUntil, time.Now().Add(failTimeout).UnixNano())
+	}
+}
+
+func (u *upstream) succeed() {
+	atomic.StoreInt32(&u.fails, 0)
+}
+
+type pool struct {
+	balancer  Balancer
+	upstreams []*upstream
+	next      uint32
+}
+
+// pick returns a healthy upstream, or any upstream if all of them are down.
+func (p *pool) pick() *upstream {
+	now := time.Now()
+	healthy := make([]*upstream, 0, len(p.upstreams))
+	for _, u := range p.upstreams {
+		if u.healthy(now) {
+			healthy = append(healthy, u)
+		}
+	}
+	if len(healthy) == 0 {
+		healthy = p.upstreams
+	}
+
+	switch p.balancer {
+	case LeastConn:
+		best := healthy[0]
+		for _, u := range healthy[1:] {
+			if atomic.LoadInt64(&u.conns) < atomic.LoadInt64(&best.conns) {
+				best = u
+			}
+		}
+		return best
+	default:
+		n := atomic.AddUint32(&p.next, 1)
+		return healthy[(n-1)%uint32(len(healthy))]
+	}
+}
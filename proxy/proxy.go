@@ -0,0 +1,179 @@
+// Package proxy provides a reverse proxy middleware, relaying requests to
+// upstream servers from inside the middleware chain.
+//
+//	router.Get("/legacy/:path*", proxy.New(proxy.Options{
+//		Targets:  []string{"http://10.0.0.1:8080", "http://10.0.0.2:8080"},
+//		Balancer: proxy.LeastConn,
+//		Rewrite:  "/v1/:path",
+//	}))
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"goblog"
+)
+
+type Options struct {
+	// Targets are the upstream base URLs, required.
+	Targets  []string
+	Balancer Balancer
+	// Rewrite is the upstream path, in which ":name" is replaced by the router
+	// parameter name. The request path is used if empty.
+	Rewrite string
+	// SetHeader and DelHeader rewrite the upstream request headers.
+	SetHeader map[string]string
+	DelHeader []string
+	// MaxFails failures in a row mark an upstream down for FailTimeout,
+	// default to 3 and 10 seconds. Transport errors and 502, 503 and 504
+	// responses are failures.
+	MaxFails    int
+	FailTimeout time.Duration
+	// FlushInterval is passed to httputil.ReverseProxy, negative to flush
+	// immediately after each write. Streaming responses always flush immediately.
+	FlushInterval time.Duration
+	Transport     http.RoundTripper
+}
+
+type proxyCtxKey struct{}
+
+// New returns the reverse proxy middleware. It ends the response with the
+// upstream response, or returns a 502 error if no upstream responded.
+func New(opts Options) goblog.Middleware {
+	if len(opts.Targets) == 0 {
+		panic(goblog.Err.WithMsg("proxy: Options.Targets required"))
+	}
+	if opts.MaxFails <= 0 {
+		opts.MaxFails = 3
+	}
+	if opts.FailTimeout <= 0 {
+		opts.FailTimeout = 10 * time.Second
+	}
+
+	p := &pool{balancer: opts.Balancer}
+	for _, target := range opts.Targets {
+		u, err := url.Parse(target)
+		if err != nil {
+			panic(goblog.Err.WithMsgf("proxy: invalid target %q: %s", target, err))
+		}
+		p.upstreams = append(p.upstreams, &upstream{url: u})
+	}
+	delHeader := make([]string, len(opts.DelHeader))
+	for i, name := range opts.DelHeader {
+		delHeader[i] = http.CanonicalHeaderKey(name)
+	}
+
+	rp := &httputil.ReverseProxy{
+		Transport:     opts.Transport,
+		FlushInterval: opts.FlushInterval,
+		Director: func(req *http.Request) {
+			pc := req.Context().Value(proxyCtxKey{}).(*proxyCtx)
+			target := pc.upstream.url
+
+			req.URL.Scheme = target.Scheme
+			req.URL.Host = target.Host
+			req.URL.Path = singleJoiningSlash(target.Path, pc.path)
+			req.URL.RawPath = ""
+			if target.RawQuery != "" && req.URL.RawQuery != "" {
+				req.URL.RawQuery = target.RawQuery + "&" + req.URL.RawQuery
+			} else if target.RawQuery != "" {
+				req.URL.RawQuery = target.RawQuery
+			}
+			req.Host = target.Host
+
+			req.Header.Set(goblog.HeaderXForwardedHost, pc.ctx.Host)
+			req.Header.Set(goblog.HeaderXForwardedProto, pc.ctx.Protocol())
+			for name, value := range opts.SetHeader {
+				req.Header.Set(name, value)
+			}
+			for _, name := range delHeader {
+				req.Header.Del(name)
+			}
+		},
+		ModifyResponse: func(res *http.Response) error {
+			pc := res.Request.Context().Value(proxyCtxKey{}).(*proxyCtx)
+			switch res.StatusCode {
+			case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+				pc.upstream.fail(opts.MaxFails, opts.FailTimeout)
+			default:
+				pc.upstream.succeed()
+			}
+			// upstream headers override those set by the middlewares, such as Server
+			header := pc.ctx.Res.Header()
+			for name := range res.Header {
+				header.Del(name)
+			}
+			return nil
+		},
+		ErrorHandler: func(_ http.ResponseWriter, req *http.Request, err error) {
+			pc := req.Context().Value(proxyCtxKey{}).(*proxyCtx)
+			pc.upstream.fail(opts.MaxFails, opts.FailTimeout)
+			pc.err = err
+		},
+	}
+
+	return func(ctx *goblog.Context) error {
+		u := p.pick()
+		pc := &proxyCtx{ctx: ctx, upstream: u, path: ctx.Req.URL.Path}
+		if opts.Rewrite != "" {
+			pc.path = rewritePath(opts.Rewrite, ctx)
+		}
+
+		atomic.AddInt64(&u.conns, 1)
+		defer atomic.AddInt64(&u.conns, -1)
+
+		req := ctx.Req.WithContext(context.WithValue(ctx.Context(), proxyCtxKey{}, pc))
+		rp.ServeHTTP(ctx.Res, req)
+		if pc.err != nil {
+			return goblog.ErrBadGateway.WithMsgf("upstream %s: %s", u.url.Host, pc.err)
+		}
+		return nil
+	}
+}
+
+type proxyCtx struct {
+	ctx      *goblog.Context
+	upstream *upstream
+	path     string
+	err      error
+}
+
+// rewritePath replaces ":name" in the pattern with router parameters.
+func rewritePath(pattern string, ctx *goblog.Context) string {
+	buf := new(strings.Builder)
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != ':' {
+			buf.WriteByte(pattern[i])
+			continue
+		}
+		j := i + 1
+		for j < len(pattern) && isNameChar(pattern[j]) {
+			j++
+		}
+		buf.WriteString(ctx.Param(pattern[i+1 : j]))
+		i = j - 1
+	}
+	return buf.String()
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func singleJoiningSlash(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+	return a + b
+}
@@ -0,0 +1,41 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"goblog"
+)
+
+func TestProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "upstream")
+		w.Write([]byte(r.URL.Path + " " + r.Header.Get(goblog.HeaderXForwardedHost)))
+	}))
+	defer upstream.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	app := goblog.New()
+	app.Use(New(Options{Targets: []string{down.URL, upstream.URL + "/base"}, MaxFails: 1}))
+
+	serve := func() *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, httptest.NewRequest("GET", "http://blog.com/post", nil))
+		return res
+	}
+
+	if res := serve(); res.Code != 502 {
+		t.Fatalf("expected 502 from the closed upstream, got %d", res.Code)
+	}
+	for i := 0; i < 3; i++ {
+		res := serve()
+		if res.Code != 200 || res.Body.String() != "/base/post blog.com" {
+			t.Fatalf("expected healthy upstream, got %d %q", res.Code, res.Body.String())
+		}
+		if server := res.Header().Values("Server"); len(server) != 1 || server[0] != "upstream" {
+			t.Fatalf("expected upstream Server header, got %v", server)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Package ratelimit provides a sliding window rate limiter middleware for goblog.
+//
+//	limiter := ratelimit.New(ratelimit.Options{
+//		Policy: ratelimit.Policy{Max: 100, Duration: time.Minute},
+//	})
+//	app.UseHandler(limiter)
+//
+//	// stricter policy for a single route
+//	router.Get("/search", limiter.Route("search", ratelimit.Policy{Max: 5, Duration: time.Minute}), search)
+package ratelimit
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"goblog"
+)
+
+// Policy allows Max requests per Duration for every client.
+type Policy struct {
+	Max      int
+	Duration time.Duration
+}
+
+type Options struct {
+	// Policy is the default policy used by Limiter.Serve.
+	Policy
+	// Prefix is prepended to every Store key, default to "LIMIT:".
+	Prefix string
+	// Store keeps the counters, default to a MemoryStore.
+	Store Store
+	// GetID returns the client identity, default to the client IP.
+	GetID func(ctx *goblog.Context) string
+}
+
+type Limiter struct {
+	prefix string
+	policy Policy
+	store  Store
+	getID  func(ctx *goblog.Context) string
+}
+
+func New(opts Options) *Limiter {
+	if opts.Prefix == "" {
+		opts.Prefix = "LIMIT:"
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryStore()
+	}
+	if opts.GetID == nil {
+		opts.GetID = clientIP
+	}
+	checkPolicy(opts.Policy)
+
+	return &Limiter{
+		prefix: opts.Prefix,
+		policy: opts.Policy,
+		store:  opts.Store,
+		getID:  opts.GetID,
+	}
+}
+
+// Serve implements goblog.Handler interface with the default policy.
+func (l *Limiter) Serve(ctx *goblog.Context) error {
+	return l.limit(ctx, "", l.policy)
+}
+
+// Route returns a middleware limiting requests with its own policy. The name
+// separates its counters from the default policy and from other routes.
+func (l *Limiter) Route(name string, policy Policy) goblog.Middleware {
+	checkPolicy(policy)
+	name += ":"
+	return func(ctx *goblog.Context) error {
+		return l.limit(ctx, name, policy)
+	}
+}
+
+// limit estimates the request count of the sliding window from the counters
+// of the current and the previous fixed windows.
+func (l *Limiter) limit(ctx *goblog.Context, name string, p Policy) error {
+	id := l.getID(ctx)
+	if id == "" {
+		return nil
+	}
+
+	now := time.Now()
+	window := now.Truncate(p.Duration)
+	key := l.prefix + name + id + ":"
+
+	curr, err := l.store.Incr(key+strconv.FormatInt(window.UnixNano(), 36), 2*p.Duration)
+	if err != nil {
+		return err
+	}
+	prev, err := l.store.Get(key + strconv.FormatInt(window.Add(-p.Duration).UnixNano(), 36))
+	if err != nil {
+		return err
+	}
+
+	weight := float64(p.Duration-now.Sub(window)) / float64(p.Duration)
+	count := int(curr) + int(math.Floor(float64(prev)*weight))
+	reset := window.Add(p.Duration)
+
+	remaining := p.Max - count
+	if remaining < 0 {
+		remaining = 0
+	}
+	ctx.Set(goblog.HeaderXRateLimitLimit, strconv.Itoa(p.Max))
+	ctx.Set(goblog.HeaderXRateLimitRemaining, strconv.Itoa(remaining))
+	ctx.Set(goblog.HeaderXRateLimitReset, strconv.FormatInt(reset.Unix(), 10))
+
+	if count > p.Max {
+		after := int(math.Ceil(reset.Sub(now).Seconds()))
+		ctx.Set(goblog.HeaderRetryAfter, strconv.Itoa(after))
+		return goblog.ErrTooManyRequests.WithMsgf("rate limit exceeded, retry in %d seconds", after)
+	}
+	return nil
+}
+
+func checkPolicy(p Policy) {
+	if p.Max <= 0 || p.Duration <= 0 {
+		panic(goblog.Err.WithMsg("ratelimit policy requires positive Max and Duration"))
+	}
+}
+
+func clientIP(ctx *goblog.Context) string {
+	if ip := ctx.IP(); ip != nil {
+		return ip.String()
+	}
+	return ctx.Req.RemoteAddr
+}
@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"goblog"
+)
+
+type okHandler struct{}
+
+func (okHandler) Serve(ctx *goblog.Context) error {
+	return ctx.End(200)
+}
+
+func TestLimiter(t *testing.T) {
+	app := goblog.New()
+	app.UseHandler(New(Options{Policy: Policy{Max: 2, Duration: time.Minute}}))
+	app.UseHandler(okHandler{})
+
+	for i, status := range []int{200, 200, 429} {
+		req := httptest.NewRequest("GET", "/", nil)
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+
+		if res.Code != status {
+			t.Fatalf("request %d: expected status %d, got %d", i, status, res.Code)
+		}
+		if res.Header().Get(goblog.HeaderXRateLimitLimit) != "2" {
+			t.Fatalf("request %d: missing %s header", i, goblog.HeaderXRateLimitLimit)
+		}
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore()
+	s.Incr("a", time.Millisecond)
+	if n, _ := s.Incr("a", time.Millisecond); n != 2 {
+		t.Fatalf("expected 2, got %d", n)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if n, _ := s.Get("a"); n != 0 {
+		t.Fatalf("expected expired counter, got %d", n)
+	}
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Store is the counter storage used by Limiter. Implementations must be
+// safe for concurrent use; a Redis or memcached backed Store lets several
+// processes share the same limits.
+type Store interface {
+	// Incr increments the counter of key by one and returns the new value.
+	// A missing or expired counter starts from zero and expires after ttl.
+	Incr(key string, ttl time.Duration) (int64, error)
+	// Get returns the current value of key, or 0 if it does not exist.
+	Get(key string) (int64, error)
+}
+
+type counter struct {
+	n      int64
+	expire time.Time
+}
+
+// MemoryStore is an in-process Store. Expired counters are swept lazily.
+type MemoryStore struct {
+	mu        sync.Mutex
+	counters  map[string]*counter
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: make(map[string]*counter)}
+}
+
+func (s *MemoryStore) Incr(key string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+	c, ok := s.counters[key]
+	if !ok || !now.Before(c.expire) {
+		c = &counter{expire: now.Add(ttl)}
+		s.counters[key] = c
+	}
+	c.n++
+	return c.n, nil
+}
+
+func (s *MemoryStore) Get(key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.counters[key]; ok && time.Now().Before(c.expire) {
+		return c.n, nil
+	}
+	return 0, nil
+}
+
+// sweep removes expired counters, at most once per second.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Second {
+		return
+	}
+	s.lastSweep = now
+	for key, c := range s.counters {
+		if !now.Before(c.expire) {
+			delete(s.counters, key)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Package requestid provides a middleware that accepts or generates a request ID
+// for log correlation across services.
+//
+//	app.Use(requestid.New())
+//	app.UseHandler(logging.Default())
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+
+	"goblog"
+)
+
+type ctxKey struct{}
+
+type Options struct {
+	// Header is the request and response header carrying the ID, default to X-Request-ID.
+	Header string
+	// Generator creates an ID when the request doesn't carry a valid one,
+	// default to a random UUID.
+	Generator func() string
+	// Validator checks the incoming ID, default to accept printable ASCII
+	// IDs not longer than 128 bytes.
+	Validator func(id string) bool
+}
+
+// New returns a middleware storing the request ID in the goblog.Context, in
+// the context.Context and in the response header.
+func New(options ...Options) goblog.Middleware {
+	opts := Options{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Header == "" {
+		opts.Header = goblog.HeaderXRequestID
+	}
+	if opts.Generator == nil {
+		opts.Generator = NewUUID
+	}
+	if opts.Validator == nil {
+		opts.Validator = isValid
+	}
+
+	return func(ctx *goblog.Context) error {
+		id := ctx.Get(opts.Header)
+		if !opts.Validator(id) {
+			id = opts.Generator()
+		}
+		ctx.SetAny(ctxKey{}, id)
+		ctx.WithContext(context.WithValue(ctx.Context(), ctxKey{}, id))
+		ctx.Set(opts.Header, id)
+		ctx.Res.KeepHeader(opts.Header)
+		return nil
+	}
+}
+
+// FromCtx returns the request ID of the goblog.Context, or "" if absent.
+func FromCtx(ctx *goblog.Context) string {
+	if val, err := ctx.Any(ctxKey{}); err == nil {
+		return val.(string)
+	}
+	return ""
+}
+
+// FromContext returns the request ID of the context.Context, or "" if absent.
+func FromContext(c context.Context) string {
+	id, _ := c.Value(ctxKey{}).(string)
+	return id
+}
+
+// NewUUID returns a random (version 4) UUID.
+func NewUUID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(goblog.Err.WithMsgf("requestid: %s", err))
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func isValid(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
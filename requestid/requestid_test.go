@@ -0,0 +1,51 @@
+package requestid
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"goblog"
+)
+
+func TestNew(t *testing.T) {
+	app := goblog.New()
+	app.Use(New())
+	app.Use(func(ctx *goblog.Context) error {
+		if FromCtx(ctx) != FromContext(ctx.Context()) {
+			t.Error("request ID mismatch between goblog.Context and context.Context")
+		}
+		return ctx.End(204)
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(goblog.HeaderXRequestID, "abc-123")
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	if id := res.Header().Get(goblog.HeaderXRequestID); id != "abc-123" {
+		t.Fatalf("expected incoming request ID, got %q", id)
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(goblog.HeaderXRequestID, "bad id\n")
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	if id := res.Header().Get(goblog.HeaderXRequestID); len(id) != 36 {
+		t.Fatalf("expected generated UUID, got %q", id)
+	}
+}
+
+func TestNew_CustomHeader(t *testing.T) {
+	app := goblog.New()
+	app.Use(New(Options{Header: "X-Trace-ID"}))
+	app.Use(func(ctx *goblog.Context) error {
+		return ctx.Error(goblog.ErrBadRequest.WithMsg("invalid post"))
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Trace-ID", "abc-123")
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	if res.Code != 400 || res.Header().Get("X-Trace-ID") != "abc-123" {
+		t.Fatalf("expected the request ID on the error response, got %d %q", res.Code, res.Header().Get("X-Trace-ID"))
+	}
+}
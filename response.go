@@ -3,18 +3,23 @@ package goblog
 import (
 	"net/http"
 	"regexp"
+	"sync/atomic"
 )
 
 var defaultHeaderFilterReg = regexp.MustCompile(
-	`(?i)^(accept|allow|retry-after|warning|vary|access-control-allow-|x-ratelimit-)`)
+	`(?i)^(accept|allow|retry-after|warning|vary|www-authenticate|access-control-allow-|x-ratelimit-|x-request-id|` +
+		`strict-transport-security|content-security-policy|x-frame-options|referrer-policy|permissions-policy|cross-origin-)`)
 
 type Response struct {
 	status 		int	// response Status Code
 	body 		[]byte	// the response content
 	afterHooks 	[]func()
 	endHooks 	[]func()
+	completeHooks []func()
+	written 	int64	// body bytes written, accessed atomically
 	ended 		atomicBool
 	wroteHeader atomicBool
+	keptHeaders []string
 	w 			http.ResponseWriter
 	rw 			http.ResponseWriter
 }
@@ -31,6 +36,12 @@ func (r *Response) Body() []byte {
 	return r.body
 }
 
+// Written returns the number of body bytes written, including streamed and
+// proxied responses that don't set Body.
+func (r *Response) Written() int64 {
+	return atomic.LoadInt64(&r.written)
+}
+
 func (r *Response) Set(key, value string) {
 	r.Header().Set(key, value)
 }
@@ -56,12 +67,28 @@ func (r *Response) ResetHeader(filterReg ...*regexp.Regexp) {
 	}
 	header := r.Header()
 	for key := range header {
-		if !reg.MatchString(key) {
+		if !reg.MatchString(key) && !r.isKept(key) {
 			delete(header, key)
 		}
 	}
 }
 
+// KeepHeader keeps the header on error responses, when ResetHeader clears the
+// other headers, such as a custom request ID header.
+func (r *Response) KeepHeader(key string) {
+	r.keptHeaders = append(r.keptHeaders, http.CanonicalHeaderKey(key))
+}
+
+func (r *Response) isKept(key string) bool {
+	key = http.CanonicalHeaderKey(key)
+	for _, kept := range r.keptHeaders {
+		if kept == key {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *Response) Header() http.Header {
 	return r.rw.Header()
 }
@@ -73,10 +100,20 @@ func (r *Response) Write(buf []byte) (int, error) {
 		}
 		r.WriteHeader(0)
 	}
-	return r.rw.Write(buf)
+	n, err := r.rw.Write(buf)
+	atomic.AddInt64(&r.written, int64(n))
+	return n, err
 }
 
 func (r *Response) WriteHeader(code int) {
+	// informational responses, such as 103 Early Hints relayed by a proxy,
+	// precede the final response header
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		if !r.wroteHeader.isTrue() {
+			r.rw.WriteHeader(code)
+		}
+		return
+	}
 	if !r.wroteHeader.swapTrue() {
 		return
 	}
@@ -109,6 +146,19 @@ func (r *Response) WriteHeader(code int) {
 	}
 }
 
+// Flush implements http.Flusher interface, for streaming responses.
+func (r *Response) Flush() {
+	if !r.wroteHeader.isTrue() {
+		if r.status == 0 {
+			r.status = 200
+		}
+		r.WriteHeader(0)
+	}
+	if f, ok := r.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func (r *Response) respond(status int, body []byte) (err error) {
 	r.body = body
 	r.WriteHeader(status)
@@ -13,6 +13,7 @@ type Router struct {
 	otherwise  Middleware
 	middleware Middleware
 	mds        []Middleware
+	patterns   map[*trie.Node]string
 }
 
 type RouterOptions struct {
@@ -42,6 +43,7 @@ func NewRouter(routerOptions ...RouterOptions) *Router {
 		root: opts.Root,
 		rt: opts.Root[0 : len(opts.Root)-1],
 		mds: make([]Middleware, 0),
+		patterns: make(map[*trie.Node]string),
 		trie: trie.New(trie.Options{
 			IgnoreCase:            opts.IgnoreCase,
 			FixedPathRedirect:     opts.FixedPathRedirect,
@@ -50,6 +52,12 @@ func NewRouter(routerOptions ...RouterOptions) *Router {
 	}
 }
 
+// Use adds a middleware running before the route handlers of the router.
+func (r *Router) Use(handle Middleware) {
+	r.mds = append(r.mds, handle)
+	r.middleware = Compose(r.mds...)
+}
+
 func (r *Router) Handle(method, pattern string, handlers ...Middleware) {
 	if method == "" {
 		panic(Err.WithMsg("invalid method"))
@@ -57,7 +65,9 @@ func (r *Router) Handle(method, pattern string, handlers ...Middleware) {
 	if len(handlers) == 0 {
 		panic(Err.WithMsg("invalid middleware"))
 	}
-	r.trie.Define(pattern).Handle(strings.ToUpper(method), Compose(handlers...))
+	node := r.trie.Define(pattern)
+	node.Handle(strings.ToUpper(method), Compose(handlers...))
+	r.patterns[node] = r.rt + pattern
 }
 
 func (r *Router) Get(pattern string, handlers ...Middleware) {
@@ -111,6 +121,7 @@ func (r *Router) Serve(ctx *Context) error {
 		}
 		handler = r.otherwise
 	} else {
+		ctx.SetAny(routePatternKey, r.patterns[matched.Node])
 		ok := false
 		if handler, ok = matched.Node.GetHandler(method).(Middleware); !ok {
 			// OPTIONS support
@@ -0,0 +1,138 @@
+// Package secure provides a middleware setting security headers on responses.
+//
+//	app.Use(secure.New())
+//
+//	// allow framing for a single route
+//	opts := secure.DefaultOptions
+//	opts.FrameOptions = ""
+//	router.Get("/embed", secure.New(opts), embed)
+//
+// A per-request nonce replaces "{nonce}" in the Content-Security-Policy. The
+// goblog.Renderer reads it by Nonce to render inline scripts:
+//
+//	func (r *Renderer) Render(ctx *goblog.Context, w io.Writer, name string, data interface{}) error {
+//		return r.tpl.ExecuteTemplate(w, name, map[string]interface{}{"Nonce": secure.Nonce(ctx), "Data": data})
+//	}
+package secure
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"goblog"
+)
+
+// NoncePlaceholder in the ContentSecurityPolicy is replaced by the
+// per-request nonce source, such as 'nonce-r4nd0m'.
+const NoncePlaceholder = "{nonce}"
+
+type nonceKey struct{}
+
+// Options are the security headers to set. An empty field removes the
+// header, so a route level middleware can override the app level one.
+type Options struct {
+	// HSTSMaxAge enables Strict-Transport-Security on HTTPS requests.
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubDomains bool
+	HSTSPreload           bool
+
+	ContentSecurityPolicy string
+	// ContentSecurityPolicyReportOnly sends Content-Security-Policy-Report-Only instead.
+	ContentSecurityPolicyReportOnly bool
+
+	ContentTypeNosniff        bool
+	FrameOptions              string
+	ReferrerPolicy            string
+	PermissionsPolicy         string
+	CrossOriginOpenerPolicy   string
+	CrossOriginResourcePolicy string
+	CrossOriginEmbedderPolicy string
+}
+
+// DefaultOptions is used by New without options.
+var DefaultOptions = Options{
+	HSTSMaxAge:                180 * 24 * time.Hour,
+	HSTSIncludeSubDomains:     true,
+	ContentSecurityPolicy:     "default-src 'self'; script-src 'self' " + NoncePlaceholder + "; object-src 'none'; base-uri 'self'; frame-ancestors 'self'",
+	ContentTypeNosniff:        true,
+	FrameOptions:              "SAMEORIGIN",
+	ReferrerPolicy:            "strict-origin-when-cross-origin",
+	PermissionsPolicy:         "camera=(), microphone=(), geolocation=()",
+	CrossOriginOpenerPolicy:   "same-origin",
+	CrossOriginResourcePolicy: "same-origin",
+}
+
+// New returns a middleware setting the security headers of the options,
+// default to DefaultOptions.
+func New(options ...Options) goblog.Middleware {
+	opts := DefaultOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	var hsts string
+	if opts.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.FormatInt(int64(opts.HSTSMaxAge/time.Second), 10)
+		if opts.HSTSIncludeSubDomains {
+			hsts += "; includeSubDomains"
+		}
+		if opts.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+	cspHeader := goblog.HeaderContentSecurityPolicy
+	if opts.ContentSecurityPolicyReportOnly {
+		cspHeader += "-Report-Only"
+	}
+	withNonce := strings.Contains(opts.ContentSecurityPolicy, NoncePlaceholder)
+	nosniff := ""
+	if opts.ContentTypeNosniff {
+		nosniff = "nosniff"
+	}
+
+	return func(ctx *goblog.Context) error {
+		if ctx.Protocol() == "https" {
+			set(ctx, goblog.HeaderStrictTransportSecurity, hsts)
+		}
+
+		csp := opts.ContentSecurityPolicy
+		if withNonce {
+			csp = strings.Replace(csp, NoncePlaceholder, "'nonce-"+Nonce(ctx)+"'", -1)
+		}
+		set(ctx, cspHeader, csp)
+		set(ctx, goblog.HeaderXContentTypeOptions, nosniff)
+		set(ctx, goblog.HeaderXFrameOptions, opts.FrameOptions)
+		set(ctx, goblog.HeaderReferrerPolicy, opts.ReferrerPolicy)
+		set(ctx, goblog.HeaderPermissionsPolicy, opts.PermissionsPolicy)
+		set(ctx, goblog.HeaderCrossOriginOpenerPolicy, opts.CrossOriginOpenerPolicy)
+		set(ctx, goblog.HeaderCrossOriginResourcePolicy, opts.CrossOriginResourcePolicy)
+		set(ctx, goblog.HeaderCrossOriginEmbedderPolicy, opts.CrossOriginEmbedderPolicy)
+		return nil
+	}
+}
+
+// Nonce returns the Content-Security-Policy nonce of the request, it is
+// generated on first use and stays the same during the request.
+func Nonce(ctx *goblog.Context) string {
+	if val, err := ctx.Any(nonceKey{}); err == nil {
+		return val.(string)
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(goblog.Err.WithMsgf("secure: %s", err))
+	}
+	nonce := base64.StdEncoding.EncodeToString(buf)
+	ctx.SetAny(nonceKey{}, nonce)
+	return nonce
+}
+
+func set(ctx *goblog.Context, key, value string) {
+	if value == "" {
+		ctx.Res.Del(key)
+	} else {
+		ctx.Set(key, value)
+	}
+}
@@ -0,0 +1,38 @@
+package secure
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"goblog"
+)
+
+func TestNew(t *testing.T) {
+	opts := DefaultOptions
+	opts.FrameOptions = ""
+
+	var nonce string
+	app := goblog.New()
+	app.Use(New())
+	app.Use(New(opts))
+	app.Use(func(ctx *goblog.Context) error {
+		nonce = Nonce(ctx)
+		return ctx.HTML(200, "ok")
+	})
+
+	req := httptest.NewRequest("GET", "https://example.com/", nil)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+
+	header := res.Header()
+	if !strings.Contains(header.Get(goblog.HeaderContentSecurityPolicy), "'nonce-"+nonce+"'") {
+		t.Fatalf("expected nonce %q in CSP %q", nonce, header.Get(goblog.HeaderContentSecurityPolicy))
+	}
+	if header.Get(goblog.HeaderStrictTransportSecurity) != "max-age=15552000; includeSubDomains" {
+		t.Fatalf("unexpected HSTS %q", header.Get(goblog.HeaderStrictTransportSecurity))
+	}
+	if header.Get(goblog.HeaderXFrameOptions) != "" {
+		t.Fatal("expected X-Frame-Options removed by the route options")
+	}
+}
@@ -0,0 +1,123 @@
+package goblog
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Timeout returns a middleware that sets the request deadline to timeout from
+// now, it tightens or extends the SetTimeout setting for a router or a route.
+// A timeout <= 0 removes the deadline, such as for streaming routes.
+//
+//	router.Use(goblog.Timeout(time.Minute))
+//	router.Get("/health", goblog.Timeout(time.Second), health)
+func Timeout(timeout time.Duration) Middleware {
+	return func(ctx *Context) error {
+		if timeout > 0 {
+			ctx.SetDeadline(time.Now().Add(timeout))
+		} else {
+			ctx.SetDeadline(time.Time{})
+		}
+		return nil
+	}
+}
+
+// SetDeadline changes the deadline of the request context, a zero t removes
+// the deadline. It has no effect once the context is done.
+func (ctx *Context) SetDeadline(t time.Time) {
+	ctx.timeoutCtx.setDeadline(t)
+}
+
+// timeoutContext is a context whose deadline can be changed after creation,
+// so that the contexts derived from it follow the new deadline.
+type timeoutContext struct {
+	parent   context.Context
+	mu       sync.Mutex
+	deadline time.Time
+	timer    *time.Timer
+	done     chan struct{}
+	err      error
+}
+
+func newTimeoutContext(parent context.Context, timeout time.Duration) (*timeoutContext, context.CancelFunc) {
+	c := &timeoutContext{parent: parent, done: make(chan struct{})}
+	if timeout > 0 {
+		c.setDeadline(time.Now().Add(timeout))
+	}
+	if parent.Done() != nil {
+		go func() {
+			select {
+			case <-parent.Done():
+				c.cancel(parent.Err())
+			case <-c.done:
+			}
+		}()
+	}
+	return c, func() { c.cancel(context.Canceled) }
+}
+
+func (c *timeoutContext) setDeadline(t time.Time) {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return
+	}
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.deadline = t
+	if t.IsZero() {
+		c.mu.Unlock()
+		return
+	}
+	d := time.Until(t)
+	if d > 0 {
+		c.timer = time.AfterFunc(d, func() { c.cancel(context.DeadlineExceeded) })
+	}
+	c.mu.Unlock()
+
+	if d <= 0 {
+		c.cancel(context.DeadlineExceeded)
+	}
+}
+
+func (c *timeoutContext) cancel(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	close(c.done)
+}
+
+func (c *timeoutContext) Deadline() (time.Time, bool) {
+	c.mu.Lock()
+	deadline := c.deadline
+	c.mu.Unlock()
+
+	if parent, ok := c.parent.Deadline(); ok && (deadline.IsZero() || parent.Before(deadline)) {
+		return parent, true
+	}
+	return deadline, !deadline.IsZero()
+}
+
+func (c *timeoutContext) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *timeoutContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *timeoutContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
@@ -0,0 +1,70 @@
+package tracing
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Exporter receives the finished, sampled spans. Implementations must be
+// safe for concurrent use, and should not block.
+type Exporter interface {
+	ExportSpan(span *Span) error
+}
+
+// JSONExporter writes a JSON object per span and line, for local use.
+type JSONExporter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+func NewJSONExporter(w io.Writer) *JSONExporter {
+	return &JSONExporter{w: w, enc: json.NewEncoder(w)}
+}
+
+// NewJSONFileExporter returns a JSONExporter appending to the file.
+func NewJSONFileExporter(path string) (*JSONExporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONExporter(file), nil
+}
+
+type jsonSpan struct {
+	*Span
+	TraceID    string  `json:"traceId"`
+	SpanID     string  `json:"spanId"`
+	ParentID   string  `json:"parentId,omitempty"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+func (e *JSONExporter) ExportSpan(span *Span) error {
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	js := jsonSpan{
+		Span:       span,
+		TraceID:    span.TraceID.String(),
+		SpanID:     span.SpanID.String(),
+		DurationMs: float64(span.End.Sub(span.Start)) / float64(time.Millisecond),
+	}
+	if span.ParentID.IsValid() {
+		js.ParentID = span.ParentID.String()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.enc.Encode(js)
+}
+
+// Close closes the underlying writer if it is an io.Closer.
+func (e *JSONExporter) Close() error {
+	if c, ok := e.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+type TraceID [16]byte
+
+type SpanID [8]byte
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+// SpanContext is the propagated part of a span, as in the W3C Trace Context.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string
+}
+
+// ParseTraceparent parses a "traceparent" header, such as
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func ParseTraceparent(traceparent, tracestate string) (SpanContext, bool) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return sc, false
+	}
+	// version 00 has exactly 4 fields, future versions may append fields
+	if parts[0] == "00" && len(parts) != 4 {
+		return sc, false
+	}
+	if !decodeHex(sc.TraceID[:], parts[1]) || !decodeHex(sc.SpanID[:], parts[2]) {
+		return sc, false
+	}
+	var flags [1]byte
+	if !decodeHex(flags[:], parts[3]) || !sc.TraceID.IsValid() || !sc.SpanID.IsValid() {
+		return sc, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+	if len(tracestate) <= 512 {
+		sc.TraceState = strings.TrimSpace(tracestate)
+	}
+	return sc, true
+}
+
+func decodeHex(dst []byte, s string) bool {
+	if len(s) != 2*len(dst) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// Traceparent formats the "traceparent" header of the span context.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// Span is a timed operation of a trace.
+type Span struct {
+	mu         sync.Mutex
+	tracer     *Tracer
+	ended      bool
+	Name       string                 `json:"name"`
+	Kind       string                 `json:"kind"`
+	TraceID    TraceID                `json:"-"`
+	SpanID     SpanID                 `json:"-"`
+	ParentID   SpanID                 `json:"-"`
+	Sampled    bool                   `json:"-"`
+	TraceState string                 `json:"traceState,omitempty"`
+	Start      time.Time              `json:"start"`
+	End        time.Time              `json:"end"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// Context returns the propagated part of the span.
+func (s *Span) Context() SpanContext {
+	return SpanContext{TraceID: s.TraceID, SpanID: s.SpanID, Sampled: s.Sampled, TraceState: s.TraceState}
+}
+
+func (s *Span) SetName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Name = name
+}
+
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Attributes == nil {
+		s.Attributes = make(map[string]interface{})
+	}
+	s.Attributes[key] = value
+}
+
+// SetError marks the span failed.
+func (s *Span) SetError(msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Error = msg
+}
+
+// Finish ends the span and exports it if sampled, only the first call counts.
+func (s *Span) Finish() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.End = time.Now()
+	s.mu.Unlock()
+
+	if s.Sampled && s.tracer != nil {
+		s.tracer.export(s)
+	}
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of the context carrying the span.
+func ContextWithSpan(c context.Context, span *Span) context.Context {
+	return context.WithValue(c, spanKey{}, span)
+}
+
+// SpanFromContext returns the span of the context, or nil.
+func SpanFromContext(c context.Context) *Span {
+	span, _ := c.Value(spanKey{}).(*Span)
+	return span
+}
+
+func newSpanID() (id SpanID) {
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return
+}
+
+func newTraceID() (id TraceID) {
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return
+}
@@ -0,0 +1,153 @@
+// Package tracing provides distributed tracing with W3C Trace Context
+// propagation.
+//
+//	exporter, _ := tracing.NewJSONFileExporter("spans.log")
+//	tracer := tracing.New(tracing.Options{Exporter: exporter})
+//	app.UseHandler(tracer)  // a span around the middleware chain
+//	router.Use(tracer.Route) // a span around the route handler
+//
+// The spans are stored in ctx.Context(), child spans can be started from it
+// and propagated to outgoing requests:
+//
+//	c, span := tracer.Start(ctx.Context(), "db.query")
+//	defer span.Finish()
+//	tracing.Inject(c, req.Header)
+package tracing
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"goblog"
+)
+
+// W3C Trace Context headers.
+const (
+	HeaderTraceparent = "traceparent"
+	HeaderTracestate  = "tracestate"
+)
+
+type Options struct {
+	// Exporter receives the finished spans, required.
+	Exporter Exporter
+	// SampleRate is the probability to sample a new trace, default to 1.
+	// Incoming traces keep the sampling decision of the caller.
+	SampleRate float64
+	// OnError is called when the exporter fails, optional.
+	OnError func(err error)
+}
+
+type Tracer struct {
+	opts Options
+}
+
+func New(opts Options) *Tracer {
+	if opts.Exporter == nil {
+		panic(goblog.Err.WithMsg("tracing: Options.Exporter required"))
+	}
+	if opts.SampleRate <= 0 {
+		opts.SampleRate = 1
+	}
+	return &Tracer{opts: opts}
+}
+
+// Start starts a child span of the span in the context, or a new trace. The
+// returned context carries the new span.
+func (t *Tracer) Start(c context.Context, name string) (context.Context, *Span) {
+	var parent SpanContext
+	if span := SpanFromContext(c); span != nil {
+		parent = span.Context()
+	}
+	span := t.newSpan(name, "internal", parent)
+	return ContextWithSpan(c, span), span
+}
+
+func (t *Tracer) newSpan(name, kind string, parent SpanContext) *Span {
+	span := &Span{tracer: t, Name: name, Kind: kind, SpanID: newSpanID()}
+	if parent.TraceID.IsValid() {
+		span.TraceID = parent.TraceID
+		span.ParentID = parent.SpanID
+		span.Sampled = parent.Sampled
+		span.TraceState = parent.TraceState
+	} else {
+		span.TraceID = newTraceID()
+		span.Sampled = t.opts.SampleRate >= 1 || rand.Float64() < t.opts.SampleRate
+	}
+	span.Start = time.Now()
+	return span
+}
+
+// Serve implements goblog.Handler interface. It continues the trace of the
+// incoming traceparent header, or starts a new one, with a server span
+// ending when the request is complete. The span context is also responded in
+// the traceparent and tracestate headers.
+func (t *Tracer) Serve(ctx *goblog.Context) error {
+	parent, _ := ParseTraceparent(ctx.Get(HeaderTraceparent), ctx.Get(HeaderTracestate))
+	span := t.newSpan("HTTP "+ctx.Method, "server", parent)
+	span.SetAttribute("http.method", ctx.Method)
+	span.SetAttribute("http.target", ctx.Req.URL.RequestURI())
+	span.SetAttribute("http.host", ctx.Host)
+	span.SetAttribute("net.peer.ip", ctx.IP().String())
+
+	sc := span.Context()
+	ctx.Set(HeaderTraceparent, sc.Traceparent())
+	ctx.Res.KeepHeader(HeaderTraceparent)
+	if sc.TraceState != "" {
+		ctx.Set(HeaderTracestate, sc.TraceState)
+		ctx.Res.KeepHeader(HeaderTracestate)
+	}
+	t.track(ctx, span, true)
+	return nil
+}
+
+// Route is a goblog.Middleware for goblog.Router.Use, it starts a child span
+// named by the route pattern, ending when the request is complete.
+func (t *Tracer) Route(ctx *goblog.Context) error {
+	_, span := t.Start(ctx.Context(), ctx.Method+" "+ctx.RoutePattern())
+	span.Kind = "handler"
+	t.track(ctx, span, false)
+	return nil
+}
+
+func (t *Tracer) track(ctx *goblog.Context, span *Span, server bool) {
+	ctx.WithContext(ContextWithSpan(ctx.Context(), span))
+	ctx.OnComplete(func() {
+		status := ctx.Res.Status()
+		if server {
+			// the route is known only after routing
+			if pattern := ctx.RoutePattern(); pattern != "" {
+				span.SetName(ctx.Method + " " + pattern)
+				span.SetAttribute("http.route", pattern)
+			}
+			span.SetAttribute("http.status_code", status)
+		}
+		if status >= 500 {
+			span.SetError(http.StatusText(status))
+		}
+		span.Finish()
+	})
+}
+
+func (t *Tracer) export(span *Span) {
+	if err := t.opts.Exporter.ExportSpan(span); err != nil && t.opts.OnError != nil {
+		t.opts.OnError(err)
+	}
+}
+
+// Inject sets the traceparent and tracestate headers of the span in the
+// context, for outgoing requests.
+func Inject(c context.Context, header http.Header) {
+	span := SpanFromContext(c)
+	if span == nil {
+		return
+	}
+	sc := span.Context()
+	header.Set(HeaderTraceparent, sc.Traceparent())
+	if sc.TraceState != "" {
+		header.Set(HeaderTracestate, sc.TraceState)
+	} else {
+		header.Del(HeaderTracestate)
+	}
+}
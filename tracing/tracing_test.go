@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"goblog"
+)
+
+type memExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func (e *memExporter) ExportSpan(span *Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+	return nil
+}
+
+func TestParseTraceparent(t *testing.T) {
+	sc, ok := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "congo=t61rcWkgMzE")
+	if !ok || !sc.Sampled || sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.TraceState != "congo=t61rcWkgMzE" {
+		t.Fatalf("unexpected span context %+v", sc)
+	}
+	if sc.Traceparent() != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Fatalf("unexpected traceparent %s", sc.Traceparent())
+	}
+	for _, val := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+	} {
+		if _, ok := ParseTraceparent(val, ""); ok {
+			t.Errorf("expected invalid traceparent %q", val)
+		}
+	}
+}
+
+func TestTracer(t *testing.T) {
+	exporter := &memExporter{}
+	tracer := New(Options{Exporter: exporter})
+	app := goblog.New()
+	done := make(chan struct{}, 1)
+	app.Use(func(ctx *goblog.Context) error {
+		// complete hooks run asynchronously in LIFO order, after the tracing hook
+		ctx.OnComplete(func() { done <- struct{}{} })
+		return nil
+	})
+	app.UseHandler(tracer)
+	app.Use(func(ctx *goblog.Context) error {
+		header := http.Header{}
+		Inject(ctx.Context(), header)
+		return ctx.HTML(200, header.Get(HeaderTraceparent))
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(HeaderTracestate, "congo=t61rcWkgMzE")
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	<-done
+
+	exporter.mu.Lock()
+	defer exporter.mu.Unlock()
+	if len(exporter.spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(exporter.spans))
+	}
+	span := exporter.spans[0]
+	if span.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || span.ParentID.String() != "00f067aa0ba902b7" {
+		t.Fatalf("expected span of the incoming trace, got %s %s", span.TraceID, span.ParentID)
+	}
+	if res.Body.String() != span.Context().Traceparent() {
+		t.Fatalf("expected propagated traceparent %s, got %s", span.Context().Traceparent(), res.Body.String())
+	}
+	if res.Header().Get(HeaderTraceparent) != span.Context().Traceparent() || res.Header().Get(HeaderTracestate) != "congo=t61rcWkgMzE" {
+		t.Fatalf("expected the span context in the response headers, got %q %q",
+			res.Header().Get(HeaderTraceparent), res.Header().Get(HeaderTracestate))
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"encoding"
 	"encoding/json"
 	"sync/atomic"
+	"errors"
 )
 
 type middlewares []Middleware
@@ -38,6 +39,15 @@ func Compose(mds ...Middleware) Middleware {
 
 var noOp Middleware = func(ctx *Context) error { return nil }
 
+// WrapHandler wraps a http.Handler as a Middleware, the request carries the
+// context of ctx.
+func WrapHandler(handler http.Handler) Middleware {
+	return func(ctx *Context) error {
+		handler.ServeHTTP(ctx.Res, ctx.Req.WithContext(ctx.Context()))
+		return nil
+	}
+}
+
 type atomicBool int32
 
 
@@ -57,10 +67,12 @@ func IsNil(val interface{}) bool {
 
 type Error struct {
 	Code  int          `json:"-"`
+	AppCode string     `json:"code,omitempty"`
 	Err   string       `json:"error"`
 	Msg   string 	   `json:"message"`
 	Data  interface{} `json:"data,omitempty"`
 	Stack string       `json:"-"`
+	cause error
 }
 
 func (err *Error) Status() int {
@@ -71,6 +83,21 @@ func (err *Error) Error() string {
 	return fmt.Sprintf("%s: %s", err.Err, err.Msg)
 }
 
+// Unwrap returns the cause of the error, if created by From.
+func (err *Error) Unwrap() error {
+	return err.cause
+}
+
+// Is reports whether the target is an *Error with the same code, and the same
+// AppCode if the target has one, so that errors.Is(err, ErrNotFound) matches
+// any 404 error.
+func (err *Error) Is(target error) bool {
+	if t, ok := target.(*Error); ok {
+		return t.Code == err.Code && (t.AppCode == "" || t.AppCode == err.AppCode)
+	}
+	return false
+}
+
 func (err Error) String() string {
 	return err.GoString()
 }
@@ -79,8 +106,8 @@ func (err Error) GoString() string {
 	if v, ok := err.Data.([]byte); ok && utf8.Valid(v) {
 		err.Data = string(v)
 	}
-	return fmt.Sprintf(`Error{Code:%d, Err:"%s", Msg:"%s", Data:%#v, Stack:"%s"}`,
-		err.Code, err.Err, err.Msg, err.Data, err.Stack)
+	return fmt.Sprintf(`Error{Code:%d, AppCode:"%s", Err:"%s", Msg:"%s", Data:%#v, Stack:"%s"}`,
+		err.Code, err.AppCode, err.Err, err.Msg, err.Data, err.Stack)
 }
 
 func (err Error) WithMsg(msgs ...string) *Error {
@@ -94,6 +121,13 @@ func (err Error) WithMsgf(format string, args ...interface{}) *Error {
 	return err.WithMsg(fmt.Sprintf(format, args...))
 }
 
+// WithAppCode returns a copy of the error with the application error code,
+// such as "POST_NOT_FOUND", responded as "code".
+func (err Error) WithAppCode(code string) *Error {
+	err.AppCode = code
+	return &err
+}
+
 func (err Error) WithCode(code int) *Error {
 	err.Code = code
 	if text := http.StatusText(code); text != "" {
@@ -102,23 +136,34 @@ func (err Error) WithCode(code int) *Error {
 	return &err
 }
 
+// From converts the error to an *Error, keeping it as the cause. An *Error,
+// HTTPError or *textproto.Error wrapped in the error chain sets the code.
 func (err Error) From(e error) *Error {
 	if IsNil(e) {
 		return nil
 	}
-
-	switch v := e.(type) {
-	case *Error:
+	if v, ok := e.(*Error); ok {
 		return v
-	case HTTPError:
-		err.Code = v.Status()
-		err.Msg = v.Error()
-	case *textproto.Error:
-		err.Code = v.Code
-		err.Msg = v.Msg
+	}
+
+	var (
+		ge *Error
+		he HTTPError
+		te *textproto.Error
+	)
+	switch {
+	case errors.As(e, &ge):
+		err = *ge
+	case errors.As(e, &he):
+		err = *err.WithCode(he.Status())
+		err.Msg = he.Error()
+	case errors.As(e, &te):
+		err = *err.WithCode(te.Code)
+		err.Msg = te.Msg
 	default:
 		err.Msg = e.Error()
 	}
+	err.cause = e
 
 	if err.Err == "" {
 		err.Err = http.StatusText(err.Code)
@@ -131,20 +176,22 @@ func ParseError(e error, code ...int) HTTPError {
 		return nil
 	}
 
-	switch v := e.(type) {
-	case HTTPError:
+	if v, ok := e.(HTTPError); ok {
 		return v
-	case *textproto.Error:
-		err := Err.WithCode(v.Code)
-		err.Msg = v.Msg
-		return err
-	default:
-		err := ErrInternalServerError.WithMsg(e.Error())
-		if len(code) > 0 && code[0] > 0 {
-			err = err.WithCode(code[0])
-		}
-		return err
 	}
+
+	var (
+		he HTTPError
+		te *textproto.Error
+	)
+	if errors.As(e, &he) || errors.As(e, &te) {
+		return Err.From(e)
+	}
+	err := ErrInternalServerError.From(e)
+	if len(code) > 0 && code[0] > 0 {
+		err = err.WithCode(code[0])
+	}
+	return err
 }
 
 func ErrorWithStack(val interface{}, skip ...int) *Error {
@@ -1,6 +1,13 @@
 package goblog
 
-import "testing"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"testing"
+)
 
 func TestError_From(t *testing.T) {
 	var err Error
@@ -8,4 +15,57 @@ func TestError_From(t *testing.T) {
 	var e error
 	e = nil
 	err.From(e)
-}
\ No newline at end of file
+}
+
+func TestError_Wrap(t *testing.T) {
+	wrapped := fmt.Errorf("load post: %w", ErrNotFound.WithMsg("post 1 not found"))
+
+	err := ParseError(wrapped)
+	if err.Status() != 404 || err.Error() != "Not Found: post 1 not found" {
+		t.Fatalf("expected wrapped 404, got %v", err)
+	}
+	if !errors.Is(err, ErrNotFound) || errors.Is(err, ErrBadRequest) || errors.Unwrap(err.(*Error)) != wrapped {
+		t.Fatalf("expected cause chain, got %#v", err)
+	}
+	if e := ErrorWithStack(wrapped); e.Code != 404 || e.Stack == "" {
+		t.Fatalf("expected wrapped 404 with stack, got %#v", e)
+	}
+
+	err = ParseError(fmt.Errorf("smtp: %w", &textproto.Error{Code: 503, Msg: "busy"}))
+	if err.Status() != 503 || err.Error() != "Service Unavailable: busy" {
+		t.Fatalf("expected wrapped 503, got %v", err)
+	}
+
+	deadline := fmt.Errorf("query: %w", context.DeadlineExceeded)
+	if err = ParseError(deadline); err.Status() != 500 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected 500 with cause, got %v", err)
+	}
+}
+
+func TestErrorCatalog(t *testing.T) {
+	catalog := NewErrorCatalog()
+	errPostNotFound := catalog.Define(ErrNotFound, "POST_NOT_FOUND", "post not found")
+	catalog.Define(ErrConflict, "POST_EXISTS", "post exists")
+
+	err := errPostNotFound.WithMsg("post 1 not found")
+	if !errors.Is(err, errPostNotFound) || !errors.Is(err, ErrNotFound) || errors.Is(ErrNotFound, errPostNotFound) {
+		t.Fatal("expected errors.Is to match the status and app code")
+	}
+	if buf, _ := json.Marshal(err); string(buf) != `{"code":"POST_NOT_FOUND","error":"Not Found","message":"post 1 not found"}` {
+		t.Fatalf("unexpected error JSON %s", buf)
+	}
+
+	buf, _ := json.Marshal(catalog)
+	expected := `[{"code":"POST_EXISTS","status":409,"error":"Conflict","message":"post exists"},` +
+		`{"code":"POST_NOT_FOUND","status":404,"error":"Not Found","message":"post not found"}]`
+	if string(buf) != expected {
+		t.Fatalf("expected %s, got %s", expected, buf)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected duplicate app code panic")
+		}
+	}()
+	catalog.Define(ErrNotFound, "POST_NOT_FOUND", "")
+}